@@ -0,0 +1,107 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+//Handle is an opaque reference to a finder, sender, receiver or router owned by a Backend.
+//A zero handle means that the instance could not be created.
+type Handle uintptr
+
+//APILevel is the version of the function table a backend was loaded with. Functions that were added after the
+//negotiated level return ErrNotSupported.
+type APILevel int
+
+const (
+	APILevel3 APILevel = 3
+	APILevel4 APILevel = 4
+	APILevel5 APILevel = 5
+)
+
+//Backend is what every function in this package calls into. LoadAndInitialize installs a backend that drives the
+//function table exported by the NDI runtime library, LoadAndInitializeBackend installs any other implementation such
+//as FakeBackend. The methods mirror the NDIlib_* functions of the same name and take the same C layout structures.
+type Backend interface {
+	Initialize() error
+	Destroy() error
+	APILevel() APILevel
+	Version() (string, error)
+	IsSupportedCPU() (bool, error)
+
+	FindCreateV2(settings *FindCreateSettings) (Handle, error)
+	FindDestroy(inst Handle) error
+	FindWaitForSources(inst Handle, timeoutInMs uint32) (bool, error)
+
+	//The returned sources are owned by the backend and stay valid until the next call for the same finder
+	//or until the finder is destroyed.
+	FindGetCurrentSources(inst Handle) ([]*Source, error)
+
+	SendCreate(settings *SendCreateSettings) (Handle, error)
+	SendDestroy(inst Handle) error
+	SendSendVideoV2(inst Handle, vf *VideoFrameV2) error
+	SendSendVideoAsyncV2(inst Handle, vf *VideoFrameV2) error
+	SendSendAudioV2(inst Handle, af *AudioFrameV2) error
+	SendSendMetadata(inst Handle, mf *MetadataFrame) error
+	SendCapture(inst Handle, mf *MetadataFrame, timeoutInMs uint32) (FrameType, error)
+	SendFreeMetadata(inst Handle, mf *MetadataFrame) error
+	SendGetTally(inst Handle, tally *Tally, timeoutInMs uint32) (bool, error)
+	SendGetNoConnections(inst Handle, timeoutInMs uint32) (int, error)
+	SendSetFailover(inst Handle, source *Source) error
+	SendClearConnectionMetadata(inst Handle) error
+	SendAddConnectionMetadata(inst Handle, mf *MetadataFrame) error
+
+	RecvCreateV2(settings *RecvCreateSettings) (Handle, error)
+	RecvDestroy(inst Handle) error
+	RecvCaptureV2(inst Handle, vf *VideoFrameV2, af *AudioFrameV2, mf *MetadataFrame, timeoutInMs uint32) (FrameType, error)
+	RecvFreeVideoV2(inst Handle, vf *VideoFrameV2) error
+	RecvFreeAudioV2(inst Handle, af *AudioFrameV2) error
+	RecvFreeMetadata(inst Handle, mf *MetadataFrame) error
+	RecvSendMetadata(inst Handle, mf *MetadataFrame) (bool, error)
+	RecvSetTally(inst Handle, tally *Tally) (bool, error)
+	RecvGetNoConnections(inst Handle, timeoutInMs uint32) (int, error)
+	RecvGetPerformance(inst Handle, total, dropped *RecvPerformance) error
+	RecvGetQueue(inst Handle, total *RecvQueue) error
+	RecvClearConnectionMetadata(inst Handle) error
+	RecvAddConnectionMetadata(inst Handle, mf *MetadataFrame) error
+	RecvPtzIsSupported(inst Handle) (bool, error)
+	RecvPtzZoom(inst Handle, zoom float32) (bool, error)
+	RecvPtzZoomSpeed(inst Handle, speed float32) (bool, error)
+	RecvPtzPanTilt(inst Handle, pan, tilt float32) (bool, error)
+	RecvPtzPanTiltSpeed(inst Handle, panSpeed, tiltSpeed float32) (bool, error)
+	RecvPtzStorePreset(inst Handle, preset int) (bool, error)
+	RecvPtzRecallPreset(inst Handle, preset int, speed float32) (bool, error)
+	RecvPtzAutoFocus(inst Handle) (bool, error)
+	RecvPtzFocus(inst Handle, focus float32) (bool, error)
+	RecvPtzFocusSpeed(inst Handle, speed float32) (bool, error)
+	RecvPtzWhiteBalanceAuto(inst Handle) (bool, error)
+	RecvPtzWhiteBalanceIndoor(inst Handle) (bool, error)
+	RecvPtzWhiteBalanceOutdoor(inst Handle) (bool, error)
+	RecvPtzWhiteBalanceOneshot(inst Handle) (bool, error)
+	RecvPtzWhiteBalanceManual(inst Handle, red, blue float32) (bool, error)
+	RecvPtzExposureAuto(inst Handle) (bool, error)
+	RecvPtzExposureManual(inst Handle, level float32) (bool, error)
+	RecvPtzExposureManualV2(inst Handle, iris, gain, shutterSpeed float32) (bool, error)
+	RecvRecordingIsSupported(inst Handle) (bool, error)
+	RecvRecordingStart(inst Handle, filenameHint *byte) (bool, error)
+	RecvRecordingStop(inst Handle) (bool, error)
+	RecvRecordingSetAudioLevel(inst Handle, levelDB float32) (bool, error)
+	RecvRecordingIsRecording(inst Handle) (bool, error)
+	RecvRecordingGetTimes(inst Handle, times *RecvRecordingTime) (bool, error)
+
+	//The returned strings are nil when there is none, otherwise they must be freed with RecvFreeString.
+	RecvRecordingGetFilename(inst Handle) (*byte, error)
+	RecvRecordingGetError(inst Handle) (*byte, error)
+	RecvFreeString(inst Handle, s *byte) error
+
+	RoutingCreate(settings *RoutingCreateSettings) (Handle, error)
+	RoutingDestroy(inst Handle) error
+	RoutingChange(inst Handle, source *Source) (bool, error)
+	RoutingClear(inst Handle) (bool, error)
+
+	UtilSendSendAudioInterleaved16s(inst Handle, af *AudioFrameInterleaved16s) error
+	UtilSendSendAudioInterleaved32f(inst Handle, af *AudioFrameInterleaved32f) error
+	UtilAudioToInterleaved16sV2(src *AudioFrameV2, dst *AudioFrameInterleaved16s) error
+	UtilAudioFromInterleaved16sV2(src *AudioFrameInterleaved16s, dst *AudioFrameV2) error
+	UtilAudioToInterleaved32fV2(src *AudioFrameV2, dst *AudioFrameInterleaved32f) error
+	UtilAudioFromInterleaved32fV2(src *AudioFrameInterleaved32f, dst *AudioFrameV2) error
+}
@@ -0,0 +1,1472 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const fakeVersion = "fake backend"
+
+var (
+	invalidHandleErr  = errors.New("invalid instance handle")
+	notCapturedErr    = errors.New("frame was not captured by this receiver or was already freed")
+	notAllocatedErr   = errors.New("string was not handed out or was already freed")
+	destroyedInUseErr = errors.New("instance was destroyed while a call was still using it")
+)
+
+//FakeFrame is a frame that went through a FakeBackend. Video, Audio or Metadata is set according to Type and owns a
+//copy of the frame data.
+type FakeFrame struct {
+	Type     FrameType
+	Video    *VideoFrameV2
+	Audio    *AudioFrameV2
+	Metadata *MetadataFrame
+}
+
+func (f FakeFrame) clone() FakeFrame {
+	switch f.Type {
+	case FrameTypeVideo:
+		f.Video = cloneVideoFrameV2(f.Video)
+	case FrameTypeAudio:
+		f.Audio = cloneAudioFrameV2(f.Audio)
+	case FrameTypeMetadata:
+		f.Metadata = cloneMetadataFrame(f.Metadata)
+	}
+	return f
+}
+
+type fakeSource struct {
+	name, address string
+	groups        []string
+	local         bool
+}
+
+type fakeFinder struct {
+	showLocalSources bool
+	groups           []string
+	seen             uint64
+	sources          []*Source
+}
+
+type fakeSender struct {
+	name, address string
+	groups        []string
+	tally         Tally
+	failover      string
+	connection    []*MetadataFrame
+}
+
+type fakeReceiver struct {
+	source         string
+	queue          []FakeFrame
+	tally          Tally
+	connection     []*MetadataFrame
+	total, dropped RecvPerformance
+
+	//The number of captures waiting for a frame, the runtime would crash if the receiver was destroyed meanwhile.
+	busy int
+
+	//The data of the frames captured that were not freed yet.
+	captured []unsafe.Pointer
+}
+
+//Counts a frame of the given type in p.
+func (p *RecvPerformance) count(ft FrameType) {
+	switch ft {
+	case FrameTypeVideo:
+		p.VideoFrames++
+	case FrameTypeAudio:
+		p.AudioFrames++
+	case FrameTypeMetadata:
+		p.MetadataFrames++
+	}
+}
+
+//FakePTZ is the state of a PTZ camera simulated by a FakeBackend, as left by the commands it was sent.
+type FakePTZ struct {
+	Zoom, ZoomSpeed                float32
+	Pan, Tilt, PanSpeed, TiltSpeed float32
+
+	//The last preset recalled, -1 if none was, and the speed it was recalled with.
+	Preset      int
+	RecallSpeed float32
+
+	AutoFocus         bool
+	Focus, FocusSpeed float32
+
+	//One of "auto", "indoor", "outdoor", "oneshot" and "manual", with Red and Blue set for "manual".
+	WhiteBalance string
+	Red, Blue    float32
+
+	AutoExposure             bool
+	Exposure                 float32
+	Iris, Gain, ShutterSpeed float32
+}
+
+type fakeCamera struct {
+	state   FakePTZ
+	presets map[int]FakePTZ
+}
+
+//FakeRecording is the state of the recorder of a source simulated by a FakeBackend.
+type FakeRecording struct {
+	Recording bool
+
+	//The name of the file of the last recording, derived from the filename hint it was started with.
+	Filename string
+
+	//The audio level in dB.
+	AudioLevel float32
+
+	//The number of video frames delivered to the source since the recording started.
+	Frames int64
+
+	//The error reported to receivers, set with SetRecordingError.
+	Error string
+}
+
+type fakeRecorder struct {
+	state       FakeRecording
+	start, last int64
+}
+
+//Returns the current time in UTC at 100ns intervals since the Unix epoch, like the recording times of the runtime.
+func fakeTimecode() int64 {
+	return time.Now().UnixNano() / 100
+}
+
+type fakeRouter struct {
+	name, address string
+	groups        []string
+	target        string
+}
+
+//The first port handed out to senders and routers, like the runtime does.
+const fakeBasePort = 5961
+
+//FakeBackend is an in-process Backend that needs neither the NDI runtime nor a network, so that code built on this
+//package can be tested anywhere. It behaves like a network that only reaches this process: senders and routers are
+//listed by finders as "MACHINE (name)" using MachineName next to the sources added with AddSource, and receivers
+//connect to any of them by name. Frames sent by a sender are delivered to its receivers and to the receivers of routers
+//switched to it, tally and metadata set by receivers flow back to the sender. Everything senders send is also recorded
+//for Sent, and Deliver hands frames to receivers as if they came from a source.
+type FakeBackend struct {
+	//The machine name used in the source names of senders and routers.
+	MachineName string
+
+	//The API level reported to callers, NewFakeBackend sets it to APILevel3.
+	Level APILevel
+
+	mu         sync.Mutex
+	changed    chan struct{}
+	nextHandle Handle
+	sources    []fakeSource
+	sourcesGen uint64
+	finders    map[Handle]*fakeFinder
+	senders    map[Handle]*fakeSender
+	receivers  map[Handle]*fakeReceiver
+	routers    map[Handle]*fakeRouter
+	sent       map[string][]FakeFrame
+	upstream   map[string][]FakeFrame
+	cameras    map[string]*fakeCamera
+	recorders  map[string]*fakeRecorder
+
+	//The strings handed out by the recording functions that were not freed yet.
+	strings map[*byte]struct{}
+
+	//The number of frames captured by receivers that were destroyed without freeing them.
+	orphaned int
+}
+
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		MachineName: "FAKE",
+		Level:       APILevel3,
+		changed:     make(chan struct{}),
+		finders:     make(map[Handle]*fakeFinder),
+		senders:     make(map[Handle]*fakeSender),
+		receivers:   make(map[Handle]*fakeReceiver),
+		routers:     make(map[Handle]*fakeRouter),
+		sent:        make(map[string][]FakeFrame),
+		upstream:    make(map[string][]FakeFrame),
+		cameras:     make(map[string]*fakeCamera),
+		recorders:   make(map[string]*fakeRecorder),
+		strings:     make(map[*byte]struct{}),
+	}
+}
+
+//Wakes up every call that is waiting for something to change.
+func (b *FakeBackend) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+//Waits until cond returns true or the timeout expires, cond is always called with the lock held.
+func (b *FakeBackend) waitLocked(timeoutInMs uint32, cond func() bool) bool {
+	if ok := cond(); ok || timeoutInMs == 0 {
+		return ok
+	}
+
+	timer := time.NewTimer(time.Duration(timeoutInMs) * time.Millisecond)
+	defer timer.Stop()
+
+	for {
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+			b.mu.Lock()
+			if cond() {
+				return true
+			}
+		case <-timer.C:
+			b.mu.Lock()
+			return cond()
+		}
+	}
+}
+
+func (b *FakeBackend) newHandleLocked() Handle {
+	b.nextHandle++
+	return b.nextHandle
+}
+
+func (b *FakeBackend) sourceName(name string) string {
+	return b.MachineName + " (" + name + ")"
+}
+
+//Splits a comma separated list of groups, no groups means the public group.
+func fakeGroups(p *byte) []string {
+	var groups []string
+	for _, g := range strings.Split(goStringFromPtr(p), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, strings.ToLower(g))
+		}
+	}
+
+	if len(groups) == 0 {
+		return []string{"public"}
+	}
+	return groups
+}
+
+func sharesGroup(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortedHandles[T any](m map[Handle]T) []Handle {
+	handles := make([]Handle, 0, len(m))
+	for h := range m {
+		handles = append(handles, h)
+	}
+
+	sort.Slice(handles, func(i, j int) bool { return handles[i] < handles[j] })
+	return handles
+}
+
+//Returns the sources added with AddSource followed by the senders and routers of this process.
+func (b *FakeBackend) allSourcesLocked() []fakeSource {
+	sources := append([]fakeSource(nil), b.sources...)
+	for _, h := range sortedHandles(b.senders) {
+		s := b.senders[h]
+		sources = append(sources, fakeSource{s.name, s.address, s.groups, true})
+	}
+
+	for _, h := range sortedHandles(b.routers) {
+		r := b.routers[h]
+		sources = append(sources, fakeSource{r.name, r.address, r.groups, true})
+	}
+	return sources
+}
+
+//Returns the sources that the finder is able to see.
+func (b *FakeBackend) visibleSourcesLocked(f *fakeFinder) []fakeSource {
+	var sources []fakeSource
+	for _, s := range b.allSourcesLocked() {
+		if (!s.local || f.showLocalSources) && sharesGroup(s.groups, f.groups) {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
+
+func (b *FakeBackend) isOnlineLocked(name string) bool {
+	for _, s := range b.allSourcesLocked() {
+		if s.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+//Returns the combined tally of every receiver connected to the source.
+func (b *FakeBackend) tallyLocked(name string) Tally {
+	var t Tally
+	for _, r := range b.receivers {
+		if r.source == name {
+			t.OnProgram = t.OnProgram || r.tally.OnProgram
+			t.OnPreview = t.OnPreview || r.tally.OnPreview
+		}
+	}
+	return t
+}
+
+func (b *FakeBackend) connectionsLocked(name string) int {
+	n := 0
+	for _, r := range b.receivers {
+		if r.source == name && b.isOnlineLocked(name) {
+			n++
+		}
+	}
+	return n
+}
+
+func (b *FakeBackend) deliverLocked(name string, f FakeFrame) int {
+	if rec, ok := b.recorders[name]; ok && rec.state.Recording && f.Type == FrameTypeVideo {
+		rec.state.Frames++
+		rec.last = fakeTimecode()
+	}
+
+	n := 0
+	for _, r := range b.receivers {
+		if r.source == name && b.isOnlineLocked(name) {
+			r.queue = append(r.queue, f.clone())
+			r.total.count(f.Type)
+			n++
+		}
+	}
+
+	if n > 0 {
+		b.notifyLocked()
+	}
+	return n
+}
+
+//Makes a source in the public group visible to every finder. Adding a source that already exists updates its address.
+func (b *FakeBackend) AddSource(name, address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range b.sources {
+		if b.sources[i].name == name {
+			b.sources[i].address = address
+			b.sourcesGen++
+			b.notifyLocked()
+			return
+		}
+	}
+
+	b.sources = append(b.sources, fakeSource{name, address, []string{"public"}, false})
+	b.sourcesGen++
+	b.notifyLocked()
+}
+
+//Removes a source added with AddSource. Receivers connected to it lose their connection.
+func (b *FakeBackend) RemoveSource(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range b.sources {
+		if b.sources[i].name == name {
+			b.sources = append(b.sources[:i], b.sources[i+1:]...)
+			b.sourcesGen++
+			b.notifyLocked()
+			return
+		}
+	}
+}
+
+//Queues a copy of the frame on every receiver connected to the named source and returns the number of receivers.
+func (b *FakeBackend) Deliver(source string, f FakeFrame) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.deliverLocked(source, f)
+}
+
+//Makes the named source a PTZ camera or a plain source again. Receivers connected to it are sent a
+//FrameTypeStatusChange frame, like the runtime does when it learns about the change.
+func (b *FakeBackend) SetPTZSupported(source string, supported bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !supported {
+		delete(b.cameras, source)
+	} else if _, ok := b.cameras[source]; !ok {
+		b.cameras[source] = &fakeCamera{
+			state:   FakePTZ{Preset: -1, AutoFocus: true, WhiteBalance: "auto", AutoExposure: true},
+			presets: make(map[int]FakePTZ),
+		}
+	}
+	b.deliverLocked(source, FakeFrame{Type: FrameTypeStatusChange})
+}
+
+//Returns the state of the camera simulated for the named source and whether it is a PTZ camera.
+func (b *FakeBackend) PTZState(source string) (FakePTZ, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.cameras[source]
+	if !ok {
+		return FakePTZ{}, false
+	}
+	return c.state, true
+}
+
+//Makes the named source able to record or not. Receivers connected to it are sent a FrameTypeStatusChange frame.
+func (b *FakeBackend) SetRecordingSupported(source string, supported bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !supported {
+		delete(b.recorders, source)
+	} else if _, ok := b.recorders[source]; !ok {
+		b.recorders[source] = &fakeRecorder{}
+	}
+	b.deliverLocked(source, FakeFrame{Type: FrameTypeStatusChange})
+}
+
+//Sets the error the recorder of the named source reports, the empty string clears it.
+func (b *FakeBackend) SetRecordingError(source, text string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if rec, ok := b.recorders[source]; ok {
+		rec.state.Error = text
+	}
+}
+
+//Returns the state of the recorder of the named source and whether the source is able to record.
+func (b *FakeBackend) RecordingState(source string) (FakeRecording, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rec, ok := b.recorders[source]
+	if !ok {
+		return FakeRecording{}, false
+	}
+	return rec.state, true
+}
+
+//Returns how many strings handed out by the recording functions were not freed with RecvFreeString yet.
+func (b *FakeBackend) AllocatedStrings() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.strings)
+}
+
+//Returns the connection metadata of the receivers connected to the named source, in the order the receivers were
+//created.
+func (b *FakeBackend) ConnectionMetadata(source string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var metadata []string
+	for _, h := range sortedHandles(b.receivers) {
+		if r := b.receivers[h]; r.source == source && b.isOnlineLocked(source) {
+			metadata = append(metadata, fakeConnectionMetadata(r.connection)...)
+		}
+	}
+	return metadata
+}
+
+//Returns the connection metadata of the sender with the given full source name.
+func (b *FakeBackend) SenderConnectionMetadata(source string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range b.senders {
+		if s.name == source {
+			return fakeConnectionMetadata(s.connection)
+		}
+	}
+	return nil
+}
+
+//Reads the connection metadata frames only when asked for, like the runtime reads them on every new connection, so
+//that frames that were not kept alive show up in tests.
+func fakeConnectionMetadata(frames []*MetadataFrame) []string {
+	var metadata []string
+	for _, mf := range frames {
+		metadata = append(metadata, goStringFromPtr(mf.Data))
+	}
+	return metadata
+}
+
+//Returns how many frames receivers captured that were not freed yet, including those of destroyed receivers.
+func (b *FakeBackend) CapturedFrames() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := b.orphaned
+	for _, r := range b.receivers {
+		n += len(r.captured)
+	}
+	return n
+}
+
+//Returns how many captures of receivers connected to the source with the given name are waiting for a frame.
+func (b *FakeBackend) WaitingCaptures(source string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := 0
+	for _, r := range b.receivers {
+		if r.source == source {
+			n += r.busy
+		}
+	}
+	return n
+}
+
+//Returns the frames sent so far by senders with the given full source name.
+func (b *FakeBackend) Sent(source string) []FakeFrame {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]FakeFrame(nil), b.sent[source]...)
+}
+
+//Returns the combined tally that receivers connected to the named source have set.
+func (b *FakeBackend) Tally(source string) Tally {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tallyLocked(source)
+}
+
+//Returns the number of receivers connected to the named source.
+func (b *FakeBackend) Connections(source string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.connectionsLocked(source)
+}
+
+func (b *FakeBackend) Initialize() error {
+	return nil
+}
+
+func (b *FakeBackend) Destroy() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.finders = make(map[Handle]*fakeFinder)
+	b.senders = make(map[Handle]*fakeSender)
+	b.receivers = make(map[Handle]*fakeReceiver)
+	b.routers = make(map[Handle]*fakeRouter)
+	b.sourcesGen++
+	b.notifyLocked()
+	return nil
+}
+
+func (b *FakeBackend) APILevel() APILevel {
+	return b.Level
+}
+
+func (b *FakeBackend) Version() (string, error) {
+	return fakeVersion, nil
+}
+
+func (b *FakeBackend) IsSupportedCPU() (bool, error) {
+	return true, nil
+}
+
+func (b *FakeBackend) FindCreateV2(settings *FindCreateSettings) (Handle, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.newHandleLocked()
+	b.finders[h] = &fakeFinder{
+		showLocalSources: settings.showLocalSources,
+		groups:           fakeGroups(settings.groups),
+	}
+	return h, nil
+}
+
+func (b *FakeBackend) FindDestroy(inst Handle) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.finders[inst]; !ok {
+		return invalidHandleErr
+	}
+	delete(b.finders, inst)
+	return nil
+}
+
+func (b *FakeBackend) FindWaitForSources(inst Handle, timeoutInMs uint32) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	f, ok := b.finders[inst]
+	if !ok {
+		return false, invalidHandleErr
+	}
+
+	changed := b.waitLocked(timeoutInMs, func() bool { return f.seen != b.sourcesGen })
+	f.seen = b.sourcesGen
+	return changed, nil
+}
+
+func (b *FakeBackend) FindGetCurrentSources(inst Handle) ([]*Source, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	f, ok := b.finders[inst]
+	if !ok {
+		return nil, invalidHandleErr
+	}
+
+	current := b.visibleSourcesLocked(f)
+	f.sources = make([]*Source, len(current))
+	for i, s := range current {
+		f.sources[i] = &Source{cStringPtr(s.name), cStringPtr(s.address)}
+	}
+
+	f.seen = b.sourcesGen
+	return f.sources, nil
+}
+
+func (b *FakeBackend) SendCreate(settings *SendCreateSettings) (Handle, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.newHandleLocked()
+	name := goStringFromPtr(settings.ndiName)
+	if name == "" {
+		name = "Sender " + strconv.Itoa(int(h))
+	}
+
+	b.senders[h] = &fakeSender{
+		name:    b.sourceName(name),
+		address: "127.0.0.1:" + strconv.Itoa(fakeBasePort+int(h)),
+		groups:  fakeGroups(settings.groups),
+	}
+
+	b.sourcesGen++
+	b.notifyLocked()
+	return h, nil
+}
+
+func (b *FakeBackend) SendDestroy(inst Handle) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.senders[inst]; !ok {
+		return invalidHandleErr
+	}
+
+	delete(b.senders, inst)
+	b.sourcesGen++
+	b.notifyLocked()
+	return nil
+}
+
+func (b *FakeBackend) send(inst Handle, f FakeFrame) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.senders[inst]
+	if !ok {
+		return invalidHandleErr
+	}
+
+	b.sent[s.name] = append(b.sent[s.name], f.clone())
+	b.deliverLocked(s.name, f)
+	for _, r := range b.routers {
+		if r.target == s.name {
+			b.deliverLocked(r.name, f)
+		}
+	}
+	return nil
+}
+
+func (b *FakeBackend) SendSendVideoV2(inst Handle, vf *VideoFrameV2) error {
+	return b.send(inst, FakeFrame{Type: FrameTypeVideo, Video: vf})
+}
+
+func (b *FakeBackend) SendSendVideoAsyncV2(inst Handle, vf *VideoFrameV2) error {
+	if vf == nil {
+		return nil
+	}
+	return b.send(inst, FakeFrame{Type: FrameTypeVideo, Video: vf})
+}
+
+func (b *FakeBackend) SendSendAudioV2(inst Handle, af *AudioFrameV2) error {
+	return b.send(inst, FakeFrame{Type: FrameTypeAudio, Audio: af})
+}
+
+func (b *FakeBackend) SendSendMetadata(inst Handle, mf *MetadataFrame) error {
+	return b.send(inst, FakeFrame{Type: FrameTypeMetadata, Metadata: mf})
+}
+
+func (b *FakeBackend) SendCapture(inst Handle, mf *MetadataFrame, timeoutInMs uint32) (FrameType, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.senders[inst]
+	if !ok {
+		return FrameTypeError, invalidHandleErr
+	}
+
+	if !b.waitLocked(timeoutInMs, func() bool { return len(b.upstream[s.name]) > 0 }) {
+		return FrameTypeNone, nil
+	}
+
+	f := b.upstream[s.name][0]
+	b.upstream[s.name] = b.upstream[s.name][1:]
+	*mf = *f.Metadata
+	return FrameTypeMetadata, nil
+}
+
+func (b *FakeBackend) SendFreeMetadata(inst Handle, mf *MetadataFrame) error {
+	return nil
+}
+
+func (b *FakeBackend) SendGetTally(inst Handle, tally *Tally, timeoutInMs uint32) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.senders[inst]
+	if !ok {
+		return false, invalidHandleErr
+	}
+
+	changed := b.waitLocked(timeoutInMs, func() bool { return b.tallyLocked(s.name) != s.tally })
+	s.tally = b.tallyLocked(s.name)
+	if tally != nil {
+		*tally = s.tally
+	}
+	return changed, nil
+}
+
+func (b *FakeBackend) SendGetNoConnections(inst Handle, timeoutInMs uint32) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.senders[inst]
+	if !ok {
+		return 0, invalidHandleErr
+	}
+
+	b.waitLocked(timeoutInMs, func() bool { return b.connectionsLocked(s.name) > 0 })
+	return b.connectionsLocked(s.name), nil
+}
+
+func (b *FakeBackend) SendSetFailover(inst Handle, source *Source) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.senders[inst]
+	if !ok {
+		return invalidHandleErr
+	}
+
+	s.failover = ""
+	if source != nil {
+		s.failover = source.Name()
+	}
+	return nil
+}
+
+func (b *FakeBackend) SendClearConnectionMetadata(inst Handle) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.senders[inst]
+	if !ok {
+		return invalidHandleErr
+	}
+
+	s.connection = nil
+	return nil
+}
+
+func (b *FakeBackend) SendAddConnectionMetadata(inst Handle, mf *MetadataFrame) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.senders[inst]
+	if !ok {
+		return invalidHandleErr
+	}
+
+	s.connection = append(s.connection, mf)
+	return nil
+}
+
+func (b *FakeBackend) RecvCreateV2(settings *RecvCreateSettings) (Handle, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.newHandleLocked()
+	b.receivers[h] = &fakeReceiver{source: settings.SourceToConnectTo.Name()}
+	b.notifyLocked()
+	return h, nil
+}
+
+func (b *FakeBackend) RecvDestroy(inst Handle) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return invalidHandleErr
+	}
+
+	if r.busy > 0 {
+		return destroyedInUseErr
+	}
+
+	b.orphaned += len(r.captured)
+	delete(b.receivers, inst)
+	b.notifyLocked()
+	return nil
+}
+
+func (b *FakeBackend) RecvCaptureV2(inst Handle, vf *VideoFrameV2, af *AudioFrameV2, mf *MetadataFrame, timeoutInMs uint32) (FrameType, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return FrameTypeError, invalidHandleErr
+	}
+
+	//Frames of a type that the caller did not ask for are dropped, like the runtime does. Losing the connection to the
+	//source ends the wait as well.
+	wanted := func() bool {
+		if !b.isOnlineLocked(r.source) {
+			return true
+		}
+		for len(r.queue) > 0 {
+			switch f := r.queue[0]; {
+			case f.Type == FrameTypeVideo && vf != nil,
+				f.Type == FrameTypeAudio && af != nil,
+				f.Type == FrameTypeMetadata && mf != nil,
+				f.Type == FrameTypeStatusChange:
+				return true
+			}
+			r.dropped.count(r.queue[0].Type)
+			r.queue = r.queue[1:]
+		}
+		return false
+	}
+
+	r.busy++
+	ok = b.waitLocked(timeoutInMs, wanted)
+	r.busy--
+	if !b.isOnlineLocked(r.source) {
+		return FrameTypeError, nil
+	}
+	if !ok {
+		return FrameTypeNone, nil
+	}
+
+	f := r.queue[0]
+	r.queue = r.queue[1:]
+	switch f.Type {
+	case FrameTypeVideo:
+		*vf = *f.Video
+		r.captured = append(r.captured, unsafe.Pointer(vf.Data))
+	case FrameTypeAudio:
+		*af = *f.Audio
+		r.captured = append(r.captured, unsafe.Pointer(af.Data))
+	case FrameTypeMetadata:
+		*mf = *f.Metadata
+		r.captured = append(r.captured, unsafe.Pointer(mf.Data))
+	}
+	return f.Type, nil
+}
+
+//Forgets a frame the receiver captured, identified by its data. Frames without data can only be told apart by count.
+func (b *FakeBackend) freeCaptured(inst Handle, data unsafe.Pointer) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return invalidHandleErr
+	}
+
+	i := slices.Index(r.captured, data)
+	if i < 0 {
+		return notCapturedErr
+	}
+	r.captured = slices.Delete(r.captured, i, i+1)
+	return nil
+}
+
+func (b *FakeBackend) RecvFreeVideoV2(inst Handle, vf *VideoFrameV2) error {
+	return b.freeCaptured(inst, unsafe.Pointer(vf.Data))
+}
+
+func (b *FakeBackend) RecvFreeAudioV2(inst Handle, af *AudioFrameV2) error {
+	return b.freeCaptured(inst, unsafe.Pointer(af.Data))
+}
+
+func (b *FakeBackend) RecvFreeMetadata(inst Handle, mf *MetadataFrame) error {
+	return b.freeCaptured(inst, unsafe.Pointer(mf.Data))
+}
+
+func (b *FakeBackend) RecvSendMetadata(inst Handle, mf *MetadataFrame) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return false, invalidHandleErr
+	}
+
+	if !b.isOnlineLocked(r.source) {
+		return false, nil
+	}
+
+	f := FakeFrame{Type: FrameTypeMetadata, Metadata: mf}
+	b.upstream[r.source] = append(b.upstream[r.source], f.clone())
+	b.notifyLocked()
+	return true, nil
+}
+
+func (b *FakeBackend) RecvSetTally(inst Handle, tally *Tally) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return false, invalidHandleErr
+	}
+
+	r.tally = *tally
+	b.notifyLocked()
+	return b.isOnlineLocked(r.source), nil
+}
+
+func (b *FakeBackend) RecvGetNoConnections(inst Handle, timeoutInMs uint32) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return 0, invalidHandleErr
+	}
+
+	if b.isOnlineLocked(r.source) {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (b *FakeBackend) RecvGetPerformance(inst Handle, total, dropped *RecvPerformance) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return invalidHandleErr
+	}
+
+	if total != nil {
+		*total = r.total
+	}
+	if dropped != nil {
+		*dropped = r.dropped
+	}
+	return nil
+}
+
+func (b *FakeBackend) RecvGetQueue(inst Handle, total *RecvQueue) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return invalidHandleErr
+	}
+
+	*total = RecvQueue{}
+	for _, f := range r.queue {
+		switch f.Type {
+		case FrameTypeVideo:
+			total.VideoFrames++
+		case FrameTypeAudio:
+			total.AudioFrames++
+		case FrameTypeMetadata:
+			total.MetadataFrames++
+		}
+	}
+	return nil
+}
+
+func (b *FakeBackend) RecvClearConnectionMetadata(inst Handle) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return invalidHandleErr
+	}
+
+	r.connection = nil
+	return nil
+}
+
+func (b *FakeBackend) RecvAddConnectionMetadata(inst Handle, mf *MetadataFrame) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return invalidHandleErr
+	}
+
+	r.connection = append(r.connection, mf)
+	return nil
+}
+
+//Applies a command to the camera the receiver is connected to. Like the runtime it reports false when there is none.
+func (b *FakeBackend) ptz(inst Handle, fn func(c *fakeCamera) bool) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return false, invalidHandleErr
+	}
+
+	c, ok := b.cameras[r.source]
+	if !ok || !b.isOnlineLocked(r.source) {
+		return false, nil
+	}
+	return fn(c), nil
+}
+
+func (b *FakeBackend) RecvPtzIsSupported(inst Handle) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool { return true })
+}
+
+func (b *FakeBackend) RecvPtzZoom(inst Handle, zoom float32) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.Zoom = zoom
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvPtzZoomSpeed(inst Handle, speed float32) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.ZoomSpeed = speed
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvPtzPanTilt(inst Handle, pan, tilt float32) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.Pan, c.state.Tilt = pan, tilt
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvPtzPanTiltSpeed(inst Handle, panSpeed, tiltSpeed float32) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.PanSpeed, c.state.TiltSpeed = panSpeed, tiltSpeed
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvPtzStorePreset(inst Handle, preset int) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.presets[preset] = c.state
+		return true
+	})
+}
+
+//Recalling a preset restores the position, zoom and focus it was stored with, recalling one that was never stored fails.
+func (b *FakeBackend) RecvPtzRecallPreset(inst Handle, preset int, speed float32) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		p, ok := c.presets[preset]
+		if !ok {
+			return false
+		}
+
+		c.state.Pan, c.state.Tilt, c.state.Zoom = p.Pan, p.Tilt, p.Zoom
+		c.state.AutoFocus, c.state.Focus = p.AutoFocus, p.Focus
+		c.state.Preset, c.state.RecallSpeed = preset, speed
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvPtzAutoFocus(inst Handle) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.AutoFocus = true
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvPtzFocus(inst Handle, focus float32) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.AutoFocus, c.state.Focus = false, focus
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvPtzFocusSpeed(inst Handle, speed float32) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.AutoFocus, c.state.FocusSpeed = false, speed
+		return true
+	})
+}
+
+func (b *FakeBackend) whiteBalance(inst Handle, mode string) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.WhiteBalance = mode
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvPtzWhiteBalanceAuto(inst Handle) (bool, error) {
+	return b.whiteBalance(inst, "auto")
+}
+
+func (b *FakeBackend) RecvPtzWhiteBalanceIndoor(inst Handle) (bool, error) {
+	return b.whiteBalance(inst, "indoor")
+}
+
+func (b *FakeBackend) RecvPtzWhiteBalanceOutdoor(inst Handle) (bool, error) {
+	return b.whiteBalance(inst, "outdoor")
+}
+
+func (b *FakeBackend) RecvPtzWhiteBalanceOneshot(inst Handle) (bool, error) {
+	return b.whiteBalance(inst, "oneshot")
+}
+
+func (b *FakeBackend) RecvPtzWhiteBalanceManual(inst Handle, red, blue float32) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.WhiteBalance, c.state.Red, c.state.Blue = "manual", red, blue
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvPtzExposureAuto(inst Handle) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.AutoExposure = true
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvPtzExposureManual(inst Handle, level float32) (bool, error) {
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.AutoExposure, c.state.Exposure = false, level
+		return true
+	})
+}
+
+//Like the runtime, the fake only has this function at APILevel5.
+func (b *FakeBackend) RecvPtzExposureManualV2(inst Handle, iris, gain, shutterSpeed float32) (bool, error) {
+	if b.Level < APILevel5 {
+		return false, ErrNotSupported
+	}
+
+	return b.ptz(inst, func(c *fakeCamera) bool {
+		c.state.AutoExposure = false
+		c.state.Iris, c.state.Gain, c.state.ShutterSpeed = iris, gain, shutterSpeed
+		return true
+	})
+}
+
+//Applies a command to the recorder of the source the receiver is connected to. Like the runtime it reports false when
+//there is none.
+func (b *FakeBackend) recorder(inst Handle, fn func(rec *fakeRecorder) bool) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.receivers[inst]
+	if !ok {
+		return false, invalidHandleErr
+	}
+
+	rec, ok := b.recorders[r.source]
+	if !ok || !b.isOnlineLocked(r.source) {
+		return false, nil
+	}
+	return fn(rec), nil
+}
+
+//Hands out a copy of s that has to be freed with RecvFreeString, or nil for the empty string. The lock must be held.
+func (b *FakeBackend) allocStringLocked(s string) *byte {
+	p := cStringPtr(s)
+	if p != nil {
+		b.strings[p] = struct{}{}
+	}
+	return p
+}
+
+func (b *FakeBackend) RecvRecordingIsSupported(inst Handle) (bool, error) {
+	return b.recorder(inst, func(rec *fakeRecorder) bool { return true })
+}
+
+//The file is named after the hint with a .mov extension, or "recording.mov" without one.
+func (b *FakeBackend) RecvRecordingStart(inst Handle, filenameHint *byte) (bool, error) {
+	return b.recorder(inst, func(rec *fakeRecorder) bool {
+		name := goStringFromPtr(filenameHint)
+		if name == "" {
+			name = "recording"
+		}
+
+		now := fakeTimecode()
+		rec.state = FakeRecording{Recording: true, Filename: name + ".mov", AudioLevel: rec.state.AudioLevel}
+		rec.start, rec.last = now, now
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvRecordingStop(inst Handle) (bool, error) {
+	return b.recorder(inst, func(rec *fakeRecorder) bool {
+		rec.state.Recording = false
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvRecordingSetAudioLevel(inst Handle, levelDB float32) (bool, error) {
+	return b.recorder(inst, func(rec *fakeRecorder) bool {
+		rec.state.AudioLevel = levelDB
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvRecordingIsRecording(inst Handle) (bool, error) {
+	return b.recorder(inst, func(rec *fakeRecorder) bool { return rec.state.Recording })
+}
+
+//Like the runtime, this fails when the source is not recording.
+func (b *FakeBackend) RecvRecordingGetTimes(inst Handle, times *RecvRecordingTime) (bool, error) {
+	return b.recorder(inst, func(rec *fakeRecorder) bool {
+		if !rec.state.Recording {
+			return false
+		}
+
+		*times = RecvRecordingTime{NoFrames: rec.state.Frames, StartTime: rec.start, LastTime: rec.last}
+		return true
+	})
+}
+
+func (b *FakeBackend) RecvRecordingGetFilename(inst Handle) (*byte, error) {
+	var p *byte
+	_, err := b.recorder(inst, func(rec *fakeRecorder) bool {
+		p = b.allocStringLocked(rec.state.Filename)
+		return true
+	})
+	return p, err
+}
+
+func (b *FakeBackend) RecvRecordingGetError(inst Handle) (*byte, error) {
+	var p *byte
+	_, err := b.recorder(inst, func(rec *fakeRecorder) bool {
+		p = b.allocStringLocked(rec.state.Error)
+		return true
+	})
+	return p, err
+}
+
+func (b *FakeBackend) RecvFreeString(inst Handle, s *byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.receivers[inst]; !ok {
+		return invalidHandleErr
+	}
+
+	if _, ok := b.strings[s]; !ok {
+		return notAllocatedErr
+	}
+	delete(b.strings, s)
+	return nil
+}
+
+func (b *FakeBackend) RoutingCreate(settings *RoutingCreateSettings) (Handle, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.newHandleLocked()
+	b.routers[h] = &fakeRouter{
+		name:    b.sourceName(goStringFromPtr(settings.ndiName)),
+		address: "127.0.0.1:" + strconv.Itoa(fakeBasePort+int(h)),
+		groups:  fakeGroups(settings.groups),
+	}
+
+	b.sourcesGen++
+	b.notifyLocked()
+	return h, nil
+}
+
+func (b *FakeBackend) RoutingDestroy(inst Handle) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.routers[inst]; !ok {
+		return invalidHandleErr
+	}
+
+	delete(b.routers, inst)
+	b.sourcesGen++
+	b.notifyLocked()
+	return nil
+}
+
+func (b *FakeBackend) RoutingChange(inst Handle, source *Source) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.routers[inst]
+	if !ok {
+		return false, invalidHandleErr
+	}
+
+	r.target = ""
+	if source != nil {
+		r.target = source.Name()
+	}
+	return true, nil
+}
+
+func (b *FakeBackend) RoutingClear(inst Handle) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.routers[inst]
+	if !ok {
+		return false, invalidHandleErr
+	}
+
+	r.target = ""
+	return true, nil
+}
+
+func (b *FakeBackend) UtilSendSendAudioInterleaved16s(inst Handle, af *AudioFrameInterleaved16s) error {
+	data := make([]float32, int(af.NumChannels)*int(af.NumSamples))
+	planar := AudioFrameV2{ChannelStride: af.NumSamples * 4}
+	if len(data) > 0 {
+		planar.Data = &data[0]
+	}
+
+	if err := b.UtilAudioFromInterleaved16sV2(af, &planar); err != nil {
+		return err
+	}
+	return b.SendSendAudioV2(inst, &planar)
+}
+
+func (b *FakeBackend) UtilSendSendAudioInterleaved32f(inst Handle, af *AudioFrameInterleaved32f) error {
+	data := make([]float32, int(af.NumChannels)*int(af.NumSamples))
+	planar := AudioFrameV2{ChannelStride: af.NumSamples * 4}
+	if len(data) > 0 {
+		planar.Data = &data[0]
+	}
+
+	if err := b.UtilAudioFromInterleaved32fV2(af, &planar); err != nil {
+		return err
+	}
+	return b.SendSendAudioV2(inst, &planar)
+}
+
+//Returns a pointer to the sample of the given channel in a planar buffer.
+func planarSample(af *AudioFrameV2, channel, sample int) *float32 {
+	offset := uintptr(channel)*uintptr(af.ChannelStride) + uintptr(sample)*4
+	return (*float32)(unsafe.Add(unsafe.Pointer(af.Data), offset))
+}
+
+//Returns the factor that maps floating point audio onto 16 bit samples at the given reference level.
+func interleaved16sScale(referenceLevel int32) float64 {
+	return 32767 * math.Pow(10, -float64(referenceLevel)/20)
+}
+
+func (b *FakeBackend) UtilAudioToInterleaved16sV2(src *AudioFrameV2, dst *AudioFrameInterleaved16s) error {
+	dst.SampleRate, dst.NumChannels, dst.NumSamples, dst.Timecode = src.SampleRate, src.NumChannels, src.NumSamples, src.Timecode
+
+	n := int(src.NumChannels) * int(src.NumSamples)
+	if n == 0 {
+		return nil
+	}
+
+	out := unsafe.Slice(dst.Data, n)
+	scale := interleaved16sScale(dst.ReferenceLevel)
+	for s := 0; s < int(src.NumSamples); s++ {
+		for c := 0; c < int(src.NumChannels); c++ {
+			v := math.Round(float64(*planarSample(src, c, s)) * scale)
+			out[s*int(src.NumChannels)+c] = int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, v)))
+		}
+	}
+	return nil
+}
+
+func (b *FakeBackend) UtilAudioFromInterleaved16sV2(src *AudioFrameInterleaved16s, dst *AudioFrameV2) error {
+	dst.SampleRate, dst.NumChannels, dst.NumSamples, dst.Timecode = src.SampleRate, src.NumChannels, src.NumSamples, src.Timecode
+	if dst.ChannelStride == 0 {
+		dst.ChannelStride = src.NumSamples * 4
+	}
+
+	n := int(src.NumChannels) * int(src.NumSamples)
+	if n == 0 {
+		return nil
+	}
+
+	in := unsafe.Slice(src.Data, n)
+	scale := interleaved16sScale(src.ReferenceLevel)
+	for s := 0; s < int(src.NumSamples); s++ {
+		for c := 0; c < int(src.NumChannels); c++ {
+			*planarSample(dst, c, s) = float32(float64(in[s*int(src.NumChannels)+c]) / scale)
+		}
+	}
+	return nil
+}
+
+func (b *FakeBackend) UtilAudioToInterleaved32fV2(src *AudioFrameV2, dst *AudioFrameInterleaved32f) error {
+	dst.SampleRate, dst.NumChannels, dst.NumSamples, dst.Timecode = src.SampleRate, src.NumChannels, src.NumSamples, src.Timecode
+
+	n := int(src.NumChannels) * int(src.NumSamples)
+	if n == 0 {
+		return nil
+	}
+
+	out := unsafe.Slice(dst.Data, n)
+	for s := 0; s < int(src.NumSamples); s++ {
+		for c := 0; c < int(src.NumChannels); c++ {
+			out[s*int(src.NumChannels)+c] = *planarSample(src, c, s)
+		}
+	}
+	return nil
+}
+
+func (b *FakeBackend) UtilAudioFromInterleaved32fV2(src *AudioFrameInterleaved32f, dst *AudioFrameV2) error {
+	dst.SampleRate, dst.NumChannels, dst.NumSamples, dst.Timecode = src.SampleRate, src.NumChannels, src.NumSamples, src.Timecode
+	if dst.ChannelStride == 0 {
+		dst.ChannelStride = src.NumSamples * 4
+	}
+
+	n := int(src.NumChannels) * int(src.NumSamples)
+	if n == 0 {
+		return nil
+	}
+
+	in := unsafe.Slice(src.Data, n)
+	for s := 0; s < int(src.NumSamples); s++ {
+		for c := 0; c < int(src.NumChannels); c++ {
+			*planarSample(dst, c, s) = in[s*int(src.NumChannels)+c]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,440 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+//Returns v, panicking when err is set so that the failing call shows up in the stack trace.
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func checkErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func doFakeInit(t *testing.T) *FakeBackend {
+	fake := NewFakeBackend()
+	if err := LoadAndInitializeBackend(fake); err != nil {
+		t.Fatal(err)
+	}
+
+	if level := must(LoadedAPILevel()); level != APILevel3 {
+		t.Fatalf("Expected API level 3, got %d.", level)
+	}
+	return fake
+}
+
+//Initializes the fake backend along with a pool for the test, the pool is closed and the library unloaded when the
+//test ends.
+func withFake(t *testing.T) (*FakeBackend, *ObjectPool) {
+	fake := doFakeInit(t)
+	t.Cleanup(func() { DestroyAndUnload() })
+
+	pool := NewObjectPool()
+	t.Cleanup(func() { pool.CloseAll() })
+	return fake, pool
+}
+
+//withFake with a receive instance connected to a fake source with the given name.
+func withFakeRecv(t *testing.T, name string) (*FakeBackend, *ObjectPool, *RecvInstance) {
+	fake, pool := withFake(t)
+	return fake, pool, newFakeRecv(t, fake, pool, name)
+}
+
+func TestFakeFind(t *testing.T) {
+	fake := doFakeInit(t)
+	defer DestroyAndUnload()
+
+	pool := NewObjectPool()
+	inst := must(NewFindInstanceV2(pool.NewFindCreateSettings(true, "", "")))
+	defer inst.Destroy()
+
+	if must(inst.WaitForSources(0)) {
+		t.Error("WaitForSources reported a change without any sources.")
+	}
+
+	fake.AddSource("CAMERA (1)", "10.0.0.1:5961")
+	if !must(inst.WaitForSources(1000)) {
+		t.Fatal("WaitForSources did not report the new source.")
+	}
+
+	sources := must(inst.GetCurrentSources())
+	if len(sources) != 1 || sources[0].Name() != "CAMERA (1)" || sources[0].Address() != "10.0.0.1:5961" {
+		t.Fatalf("Unexpected sources: %v.", sources)
+	}
+
+	fake.RemoveSource("CAMERA (1)")
+	if len(must(inst.GetCurrentSources())) != 0 {
+		t.Error("Removed source is still listed.")
+	}
+}
+
+func TestFakeSendRecv(t *testing.T) {
+	fake := doFakeInit(t)
+	defer DestroyAndUnload()
+
+	pool := NewObjectPool()
+	send := must(NewSendInstance(pool.NewSendCreateSettings("test", "", true, false)))
+	defer send.Destroy()
+
+	frameData := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	frame := NewVideoFrameV2()
+	frame.FourCC = FourCCTypeBGRA
+	frame.Xres = 2
+	frame.Yres = 1
+	frame.LineStride = 8
+	frame.Data = &frameData[0]
+	checkErr(t, send.SendVideoV2(frame))
+
+	sent := fake.Sent("FAKE (test)")
+	if len(sent) != 1 || sent[0].Type != FrameTypeVideo {
+		t.Fatalf("Expected one video frame to be sent, got %v.", sent)
+	}
+	if !bytes.Equal(unsafe.Slice(sent[0].Video.Data, 8), frameData) {
+		t.Error("Sent frame data does not match.")
+	}
+
+	fake.AddSource("CAMERA (1)", "10.0.0.1:5961")
+	settings := NewRecvCreateSettings()
+	settings.SourceToConnectTo = *SourceInfo{Name: "CAMERA (1)"}.Source()
+	recv := must(NewRecvInstanceV2(settings))
+	defer recv.Destroy()
+
+	if n := must(recv.GetNumConnections(0)); n != 1 {
+		t.Errorf("Expected 1 connection, got %d.", n)
+	}
+
+	checkErr(t, recv.SetTally(&Tally{OnProgram: true}))
+	if tally := fake.Tally("CAMERA (1)"); !tally.OnProgram || tally.OnPreview {
+		t.Errorf("Unexpected tally %+v.", tally)
+	}
+
+	if n := fake.Deliver("CAMERA (1)", sent[0]); n != 1 {
+		t.Fatalf("Frame was delivered to %d receivers.", n)
+	}
+
+	var vf VideoFrameV2
+	if ft := must(recv.CaptureV2(&vf, nil, nil, 1000)); ft != FrameTypeVideo {
+		t.Fatalf("Expected a video frame, got %d.", ft)
+	}
+	defer recv.FreeVideoV2(&vf)
+
+	if vf.Xres != 2 || !bytes.Equal(unsafe.Slice(vf.Data, 8), frameData) {
+		t.Error("Captured frame does not match.")
+	}
+
+	if ft := must(recv.CaptureV2(&vf, nil, nil, 10)); ft != FrameTypeNone {
+		t.Errorf("Expected no frame, got %d.", ft)
+	}
+
+	fake.RemoveSource("CAMERA (1)")
+	if err := recv.SetTally(&Tally{}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Expected ErrNotConnected, got %v.", err)
+	}
+}
+
+func TestFakeAudioInterleaving(t *testing.T) {
+	doFakeInit(t)
+	defer DestroyAndUnload()
+
+	planar := []float32{0, 0.5, 1, -0.5, -1, 0.25}
+	src := NewAudioFrameV2()
+	src.NumChannels = 2
+	src.NumSamples = 3
+	src.ChannelStride = 3 * 4
+	src.Data = &planar[0]
+
+	interleaved := make([]int16, 6)
+	dst := AudioFrameInterleaved16s{Data: &interleaved[0]}
+	checkErr(t, AudioToInterleaved16sV2(src, &dst))
+
+	expected := []int16{0, -16384, 16384, -32767, 32767, 8192}
+	for i, v := range expected {
+		if d := interleaved[i] - v; d < -1 || d > 1 {
+			t.Errorf("Sample %d is %d, expected %d.", i, interleaved[i], v)
+		}
+	}
+
+	back := make([]float32, 6)
+	res := AudioFrameV2{Data: &back[0]}
+	checkErr(t, AudioFromInterleaved16sV2(&dst, &res))
+
+	if res.NumChannels != 2 || res.NumSamples != 3 || res.ChannelStride != 12 {
+		t.Fatalf("Unexpected frame layout %+v.", res)
+	}
+	for i, v := range planar {
+		if d := back[i] - v; d < -0.001 || d > 0.001 {
+			t.Errorf("Sample %d is %f, expected %f.", i, back[i], v)
+		}
+	}
+}
+
+func findSource(t *testing.T, inst *FindInstance, name string) *Source {
+	for {
+		for _, s := range must(inst.GetCurrentSources()) {
+			if s.Name() == name {
+				return s
+			}
+		}
+
+		if !must(inst.WaitForSources(1000)) {
+			t.Fatalf("Source %q was not found.", name)
+		}
+	}
+}
+
+func TestFakeLoopback(t *testing.T) {
+	doFakeInit(t)
+	defer DestroyAndUnload()
+
+	pool := NewObjectPool()
+	send := must(NewSendInstance(pool.NewSendCreateSettings("loopback", "", true, true)))
+	defer send.Destroy()
+
+	find := must(NewFindInstanceV2(pool.NewFindCreateSettings(true, "", "")))
+	defer find.Destroy()
+
+	settings := NewRecvCreateSettings()
+	settings.SourceToConnectTo = *findSource(t, find, "FAKE (loopback)")
+	recv := must(NewRecvInstanceV2(settings))
+	defer recv.Destroy()
+
+	if n := must(send.GetNumConnections(1000)); n != 1 {
+		t.Fatalf("Expected 1 connection on the sender, got %d.", n)
+	}
+	if n := must(recv.GetNumConnections(0)); n != 1 {
+		t.Fatalf("Expected 1 connection on the receiver, got %d.", n)
+	}
+
+	var tally Tally
+	must(send.GetTally(&tally, 0))
+	checkErr(t, recv.SetTally(&Tally{OnPreview: true}))
+	if !must(send.GetTally(&tally, 1000)) || !tally.OnPreview || tally.OnProgram {
+		t.Errorf("Sender did not see the tally, got %+v.", tally)
+	}
+
+	videoData := []byte{0x10, 0x80, 0x20, 0x80}
+	vf := NewVideoFrameV2()
+	vf.FourCC = FourCCTypeUYVY
+	vf.Xres = 2
+	vf.Yres = 1
+	vf.LineStride = 4
+	vf.Data = &videoData[0]
+	checkErr(t, send.SendVideoV2(vf))
+
+	audioData := []float32{0.1, 0.2, 0.3, 0.4}
+	af := NewAudioFrameV2()
+	af.NumSamples = 2
+	af.ChannelStride = 8
+	af.Data = &audioData[0]
+	checkErr(t, send.SendAudioV2(af))
+
+	metadata := []byte("<hello/>\x00")
+	mf := NewMetadataFrame()
+	mf.Data = &metadata[0]
+	checkErr(t, send.SendMetadata(mf))
+
+	var (
+		rvf VideoFrameV2
+		raf AudioFrameV2
+		rmf MetadataFrame
+	)
+
+	if ft := must(recv.CaptureV2(&rvf, &raf, &rmf, 1000)); ft != FrameTypeVideo {
+		t.Fatalf("Expected a video frame, got %d.", ft)
+	}
+	if rvf.Xres != 2 || rvf.FourCC != FourCCTypeUYVY || !bytes.Equal(unsafe.Slice(rvf.Data, 4), videoData) {
+		t.Errorf("Received video frame does not match.")
+	}
+	checkErr(t, recv.FreeVideoV2(&rvf))
+
+	if ft := must(recv.CaptureV2(&rvf, &raf, &rmf, 1000)); ft != FrameTypeAudio {
+		t.Fatalf("Expected an audio frame, got %d.", ft)
+	}
+	if raf.NumChannels != 2 || raf.NumSamples != 2 {
+		t.Errorf("Received audio frame has %d channels of %d samples.", raf.NumChannels, raf.NumSamples)
+	}
+	for i, v := range unsafe.Slice(raf.Data, 4) {
+		if v != audioData[i] {
+			t.Errorf("Audio sample %d is %f, expected %f.", i, v, audioData[i])
+		}
+	}
+	checkErr(t, recv.FreeAudioV2(&raf))
+
+	if ft := must(recv.CaptureV2(&rvf, &raf, &rmf, 1000)); ft != FrameTypeMetadata {
+		t.Fatalf("Expected a metadata frame, got %d.", ft)
+	}
+	if s := goStringFromPtr(rmf.Data); s != "<hello/>" {
+		t.Errorf("Received metadata %q.", s)
+	}
+	checkErr(t, recv.FreeMetadataV2(&rmf))
+
+	upstream := []byte("<upstream/>\x00")
+	mf.Data = &upstream[0]
+	checkErr(t, recv.SendMetadata(mf))
+
+	var smf MetadataFrame
+	if ft := must(send.Capture(&smf, 1000)); ft != FrameTypeMetadata {
+		t.Fatalf("Expected the sender to capture metadata, got %d.", ft)
+	}
+	if s := goStringFromPtr(smf.Data); s != "<upstream/>" {
+		t.Errorf("Sender captured metadata %q.", s)
+	}
+	checkErr(t, send.FreeMetadata(&smf))
+}
+
+func TestFakeLocalSources(t *testing.T) {
+	doFakeInit(t)
+	defer DestroyAndUnload()
+
+	pool := NewObjectPool()
+	send := must(NewSendInstance(pool.NewSendCreateSettings("studio", "studio", true, false)))
+	defer send.Destroy()
+
+	for _, c := range []struct {
+		showLocal bool
+		groups    string
+		visible   bool
+	}{
+		{true, "", false},
+		{true, "studio", true},
+		{false, "studio", false},
+		{true, "public,Studio", true},
+	} {
+		find := must(NewFindInstanceV2(pool.NewFindCreateSettings(c.showLocal, c.groups, "")))
+		if visible := len(must(find.GetCurrentSources())) == 1; visible != c.visible {
+			t.Errorf("Finder with local sources %v in groups %q: visible is %v, expected %v.", c.showLocal, c.groups, visible, c.visible)
+		}
+		find.Destroy()
+	}
+}
+
+func TestFakeRouting(t *testing.T) {
+	doFakeInit(t)
+	defer DestroyAndUnload()
+
+	pool := NewObjectPool()
+	send := must(NewSendInstance(pool.NewSendCreateSettings("camera", "", true, false)))
+	defer send.Destroy()
+
+	router := must(NewRoutingInstance(pool.NewRoutingCreateSettings("router", "")))
+	defer router.Destroy()
+
+	find := must(NewFindInstanceV2(pool.NewFindCreateSettings(true, "", "")))
+	defer find.Destroy()
+
+	must(router.Change(findSource(t, find, "FAKE (camera)")))
+
+	settings := NewRecvCreateSettings()
+	settings.SourceToConnectTo = *findSource(t, find, "FAKE (router)")
+	recv := must(NewRecvInstanceV2(settings))
+	defer recv.Destroy()
+
+	metadata := []byte("<routed/>\x00")
+	mf := NewMetadataFrame()
+	mf.Data = &metadata[0]
+	checkErr(t, send.SendMetadata(mf))
+
+	var rmf MetadataFrame
+	if ft := must(recv.CaptureV2(nil, nil, &rmf, 1000)); ft != FrameTypeMetadata {
+		t.Fatalf("Expected routed metadata, got %d.", ft)
+	}
+	checkErr(t, recv.FreeMetadataV2(&rmf))
+
+	must(router.Clear())
+	checkErr(t, send.SendMetadata(mf))
+	if ft := must(recv.CaptureV2(nil, nil, &rmf, 10)); ft != FrameTypeNone {
+		t.Errorf("Cleared router still forwarded frame type %d.", ft)
+	}
+}
+
+func TestFakeContext(t *testing.T) {
+	fake := doFakeInit(t)
+	defer DestroyAndUnload()
+
+	pool := NewObjectPool()
+	find := must(NewFindInstanceV2(pool.NewFindCreateSettings(true, "", "")))
+	defer find.Destroy()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	if _, err := find.WaitForSourcesContext(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v.", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Cancellation took %s.", d)
+	}
+
+	time.AfterFunc(20*time.Millisecond, func() { fake.AddSource("CAMERA (1)", "10.0.0.1:5961") })
+	if !must(find.WaitForSourcesContext(context.Background(), 5*time.Second)) {
+		t.Fatal("WaitForSourcesContext did not report the new source.")
+	}
+
+	settings := NewRecvCreateSettings()
+	settings.SourceToConnectTo = *findSource(t, find, "CAMERA (1)")
+	recv := must(NewRecvInstanceV2(settings))
+	defer recv.Destroy()
+
+	if n := must(recv.GetNumConnectionsContext(context.Background(), time.Second)); n != 1 {
+		t.Errorf("Expected 1 connection, got %d.", n)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := recv.CaptureV2Context(ctx, nil, nil, nil, time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v.", err)
+	}
+
+	fake.RemoveSource("CAMERA (1)")
+	if n := must(recv.GetNumConnectionsContext(context.Background(), 20*time.Millisecond)); n != 0 {
+		t.Errorf("Expected no connections after the timeout, got %d.", n)
+	}
+}
+
+func TestFakeSourceInfo(t *testing.T) {
+	fake := doFakeInit(t)
+	defer DestroyAndUnload()
+
+	fake.AddSource("CAMERA (1)", "10.0.0.1:5961")
+
+	pool := NewObjectPool()
+	defer pool.CloseAll()
+
+	find := must(NewFindInstanceV2(pool.NewFindCreateSettings(true, "", "")))
+	sources := must(find.CurrentSources())
+	checkErr(t, find.Destroy())
+
+	if len(sources) != 1 || sources[0] != (SourceInfo{"CAMERA (1)", "10.0.0.1:5961"}) {
+		t.Fatalf("Unexpected sources %v.", sources)
+	}
+
+	settings := NewRecvCreateSettings()
+	settings.SourceToConnectTo = *sources[0].Source()
+	recv := must(NewRecvInstanceV2(settings))
+	defer recv.Destroy()
+
+	if n := must(recv.GetNumConnections(0)); n != 1 {
+		t.Errorf("Expected 1 connection, got %d.", n)
+	}
+	if info := settings.SourceToConnectTo.Info(); info != sources[0] {
+		t.Errorf("Expected %v after the round trip, got %v.", sources[0], info)
+	}
+}
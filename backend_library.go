@@ -0,0 +1,479 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import "unsafe"
+
+//The function table loaders exported by the runtime, newest first.
+var ndiLoaders = []struct {
+	name  string
+	level APILevel
+	size  uintptr
+}{
+	{"NDIlib_v5_load", APILevel5, unsafe.Sizeof(ndiLIBv5{})},
+	{"NDIlib_v4_load", APILevel4, unsafe.Sizeof(ndiLIBv4{})},
+	{"NDIlib_v3_load", APILevel3, unsafe.Sizeof(ndiLIBv3{})},
+}
+
+//libraryBackend calls through the newest function table the runtime exports. The table is copied into a zeroed
+//ndiLIBv5 so that functions the runtime does not have are left as zero.
+type libraryBackend struct {
+	lib      libHandle
+	level    APILevel
+	funcPtrs *ndiLIBv5
+}
+
+func loadLibraryBackend(path string) (*libraryBackend, error) {
+	lib, err := openLibrary(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range ndiLoaders {
+		ndiLoadProc, lerr := lookupProc(lib, l.name)
+		if lerr != nil {
+			err = lerr
+			continue
+		}
+
+		ret, eno := callProc(ndiLoadProc)
+		if eno != 0 {
+			closeLibrary(lib)
+			return nil, Error{eno}
+		}
+
+		if ret == 0 {
+			closeLibrary(lib)
+			return nil, loadProcsErr
+		}
+
+		funcPtrs := new(ndiLIBv5)
+		n := int(l.size / unsafe.Sizeof(uintptr(0)))
+		copy(unsafe.Slice((*uintptr)(unsafe.Pointer(funcPtrs)), n), unsafe.Slice((*uintptr)(unsafe.Pointer(ret)), n))
+		return &libraryBackend{lib, l.level, funcPtrs}, nil
+	}
+
+	closeLibrary(lib)
+	return nil, err
+}
+
+//The runtime functions return C bool and int values, only the low bits of the return register are defined.
+func retBool(ret uintptr) bool {
+	return uint8(ret) != 0
+}
+
+func retInt(ret uintptr) int {
+	return int(int32(ret))
+}
+
+//go:uintptrescapes
+func (b *libraryBackend) call(proc uintptr, args ...uintptr) (uintptr, error) {
+	if proc == 0 {
+		return 0, ErrNotSupported
+	}
+
+	ret, eno := callProc(proc, args...)
+	if eno != 0 {
+		return 0, Error{eno}
+	}
+	return ret, nil
+}
+
+//callFloats is call for the functions that take an instance followed by floats.
+func (b *libraryBackend) callFloats(proc uintptr, inst Handle, args ...float32) (bool, error) {
+	if proc == 0 {
+		return false, ErrNotSupported
+	}
+
+	ret, eno := callProcFloats(proc, uintptr(inst), args...)
+	if eno != 0 {
+		return false, Error{eno}
+	}
+	return retBool(ret), nil
+}
+
+//Calls a function that takes only an instance and returns a C bool.
+func (b *libraryBackend) callBool(proc uintptr, inst Handle) (bool, error) {
+	ret, err := b.call(proc, uintptr(inst))
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) Initialize() error {
+	ret, err := b.call(b.funcPtrs.NDIlibInitialize)
+	if err != nil {
+		closeLibrary(b.lib)
+		return err
+	}
+
+	if !retBool(ret) {
+		closeLibrary(b.lib)
+		return initializeLibraryErr
+	}
+	return nil
+}
+
+//Destroy shuts the runtime down and unloads the library.
+func (b *libraryBackend) Destroy() error {
+	_, err := b.call(b.funcPtrs.NDIlibDestroy)
+	closeLibrary(b.lib)
+	return err
+}
+
+func (b *libraryBackend) APILevel() APILevel {
+	return b.level
+}
+
+func (b *libraryBackend) Version() (string, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibVersion)
+	if err != nil {
+		return "", err
+	}
+	return goStringFromConst(ret), nil
+}
+
+func (b *libraryBackend) IsSupportedCPU() (bool, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibIsSupportedCPU)
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) FindCreateV2(settings *FindCreateSettings) (Handle, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibFindCreateV2, uintptr(unsafe.Pointer(settings)))
+	return Handle(ret), err
+}
+
+func (b *libraryBackend) FindDestroy(inst Handle) error {
+	_, err := b.call(b.funcPtrs.NDIlibFindDestroy, uintptr(inst))
+	return err
+}
+
+func (b *libraryBackend) FindWaitForSources(inst Handle, timeoutInMs uint32) (bool, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibFindWaitForSources, uintptr(inst), uintptr(timeoutInMs))
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) FindGetCurrentSources(inst Handle) ([]*Source, error) {
+	var numSources uint32
+	ret, err := b.call(b.funcPtrs.NDIlibFindGetCurrentSources, uintptr(inst), uintptr(unsafe.Pointer(&numSources)))
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]*Source, numSources)
+	for i, s := range sources {
+		sources[i] = (*Source)(unsafe.Pointer(ret))
+		ret += unsafe.Sizeof(*s)
+	}
+	return sources, nil
+}
+
+func (b *libraryBackend) SendCreate(settings *SendCreateSettings) (Handle, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibSendCreate, uintptr(unsafe.Pointer(settings)))
+	return Handle(ret), err
+}
+
+func (b *libraryBackend) SendDestroy(inst Handle) error {
+	_, err := b.call(b.funcPtrs.NDIlibSendDestroy, uintptr(inst))
+	return err
+}
+
+func (b *libraryBackend) SendSendVideoV2(inst Handle, vf *VideoFrameV2) error {
+	_, err := b.call(b.funcPtrs.NDIlibSendSendVideoV2, uintptr(inst), uintptr(unsafe.Pointer(vf)))
+	return err
+}
+
+func (b *libraryBackend) SendSendVideoAsyncV2(inst Handle, vf *VideoFrameV2) error {
+	_, err := b.call(b.funcPtrs.NDIlibSendSendVideoAsyncV2, uintptr(inst), uintptr(unsafe.Pointer(vf)))
+	return err
+}
+
+func (b *libraryBackend) SendSendAudioV2(inst Handle, af *AudioFrameV2) error {
+	_, err := b.call(b.funcPtrs.NDIlibSendSendAudioV2, uintptr(inst), uintptr(unsafe.Pointer(af)))
+	return err
+}
+
+func (b *libraryBackend) SendSendMetadata(inst Handle, mf *MetadataFrame) error {
+	_, err := b.call(b.funcPtrs.NDIlibSendSendMetadata, uintptr(inst), uintptr(unsafe.Pointer(mf)))
+	return err
+}
+
+func (b *libraryBackend) SendCapture(inst Handle, mf *MetadataFrame, timeoutInMs uint32) (FrameType, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibSendCapture, uintptr(inst), uintptr(unsafe.Pointer(mf)), uintptr(timeoutInMs))
+	return FrameType(retInt(ret)), err
+}
+
+func (b *libraryBackend) SendFreeMetadata(inst Handle, mf *MetadataFrame) error {
+	_, err := b.call(b.funcPtrs.NDIlibSendFreeMetadata, uintptr(inst), uintptr(unsafe.Pointer(mf)))
+	return err
+}
+
+func (b *libraryBackend) SendGetTally(inst Handle, tally *Tally, timeoutInMs uint32) (bool, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibSendGetTally, uintptr(inst), uintptr(unsafe.Pointer(tally)), uintptr(timeoutInMs))
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) SendGetNoConnections(inst Handle, timeoutInMs uint32) (int, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibSendGetNoConnections, uintptr(inst), uintptr(timeoutInMs))
+	return retInt(ret), err
+}
+
+func (b *libraryBackend) SendSetFailover(inst Handle, source *Source) error {
+	_, err := b.call(b.funcPtrs.NDIlibSendSetFailover, uintptr(inst), uintptr(unsafe.Pointer(source)))
+	return err
+}
+
+func (b *libraryBackend) SendClearConnectionMetadata(inst Handle) error {
+	_, err := b.call(b.funcPtrs.NDIlibSendClearConnectionMetadata, uintptr(inst))
+	return err
+}
+
+func (b *libraryBackend) SendAddConnectionMetadata(inst Handle, mf *MetadataFrame) error {
+	_, err := b.call(b.funcPtrs.NDIlibSendAddConnectionMetadata, uintptr(inst), uintptr(unsafe.Pointer(mf)))
+	return err
+}
+
+func (b *libraryBackend) RecvCreateV2(settings *RecvCreateSettings) (Handle, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRecvCreateV2, uintptr(unsafe.Pointer(settings)))
+	return Handle(ret), err
+}
+
+func (b *libraryBackend) RecvDestroy(inst Handle) error {
+	_, err := b.call(b.funcPtrs.NDIlibRecvDestroy, uintptr(inst))
+	return err
+}
+
+func (b *libraryBackend) RecvCaptureV2(inst Handle, vf *VideoFrameV2, af *AudioFrameV2, mf *MetadataFrame, timeoutInMs uint32) (FrameType, error) {
+	ret, err := b.call(
+		b.funcPtrs.NDIlibRecvCaptureV2,
+		uintptr(inst),
+		uintptr(unsafe.Pointer(vf)),
+		uintptr(unsafe.Pointer(af)),
+		uintptr(unsafe.Pointer(mf)),
+		uintptr(timeoutInMs),
+	)
+	return FrameType(retInt(ret)), err
+}
+
+func (b *libraryBackend) RecvFreeVideoV2(inst Handle, vf *VideoFrameV2) error {
+	_, err := b.call(b.funcPtrs.NDIlibRecvFreeVideoV2, uintptr(inst), uintptr(unsafe.Pointer(vf)))
+	return err
+}
+
+func (b *libraryBackend) RecvFreeAudioV2(inst Handle, af *AudioFrameV2) error {
+	_, err := b.call(b.funcPtrs.NDIlibRecvFreeAudioV2, uintptr(inst), uintptr(unsafe.Pointer(af)))
+	return err
+}
+
+func (b *libraryBackend) RecvFreeMetadata(inst Handle, mf *MetadataFrame) error {
+	_, err := b.call(b.funcPtrs.NDIlibRecvFreeMetadata, uintptr(inst), uintptr(unsafe.Pointer(mf)))
+	return err
+}
+
+func (b *libraryBackend) RecvSendMetadata(inst Handle, mf *MetadataFrame) (bool, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRecvSendMetadata, uintptr(inst), uintptr(unsafe.Pointer(mf)))
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) RecvSetTally(inst Handle, tally *Tally) (bool, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRecvSetTally, uintptr(inst), uintptr(unsafe.Pointer(tally)))
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) RecvGetNoConnections(inst Handle, timeoutInMs uint32) (int, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRecvGetNoConnections, uintptr(inst), uintptr(timeoutInMs))
+	return retInt(ret), err
+}
+
+func (b *libraryBackend) RecvGetPerformance(inst Handle, total, dropped *RecvPerformance) error {
+	_, err := b.call(b.funcPtrs.NDIlibRecvGetPerformance, uintptr(inst), uintptr(unsafe.Pointer(total)), uintptr(unsafe.Pointer(dropped)))
+	return err
+}
+
+func (b *libraryBackend) RecvGetQueue(inst Handle, total *RecvQueue) error {
+	_, err := b.call(b.funcPtrs.NDIlibRecvGetQueue, uintptr(inst), uintptr(unsafe.Pointer(total)))
+	return err
+}
+
+func (b *libraryBackend) RecvClearConnectionMetadata(inst Handle) error {
+	_, err := b.call(b.funcPtrs.NDIlibRecvClearConnectionMetadata, uintptr(inst))
+	return err
+}
+
+func (b *libraryBackend) RecvAddConnectionMetadata(inst Handle, mf *MetadataFrame) error {
+	_, err := b.call(b.funcPtrs.NDIlibRecvAddConnectionMetadata, uintptr(inst), uintptr(unsafe.Pointer(mf)))
+	return err
+}
+
+func (b *libraryBackend) RecvPtzIsSupported(inst Handle) (bool, error) {
+	return b.callBool(b.funcPtrs.NDIlibRecvPtzIsSupported, inst)
+}
+
+func (b *libraryBackend) RecvPtzZoom(inst Handle, zoom float32) (bool, error) {
+	return b.callFloats(b.funcPtrs.NDIlibRecvPtzZoom, inst, zoom)
+}
+
+func (b *libraryBackend) RecvPtzZoomSpeed(inst Handle, speed float32) (bool, error) {
+	return b.callFloats(b.funcPtrs.NDIlibRecvPtzZoomSpeed, inst, speed)
+}
+
+func (b *libraryBackend) RecvPtzPanTilt(inst Handle, pan, tilt float32) (bool, error) {
+	return b.callFloats(b.funcPtrs.NDIlibRecvPtzPanTilt, inst, pan, tilt)
+}
+
+func (b *libraryBackend) RecvPtzPanTiltSpeed(inst Handle, panSpeed, tiltSpeed float32) (bool, error) {
+	return b.callFloats(b.funcPtrs.NDIlibRecvPtzPanTiltSpeed, inst, panSpeed, tiltSpeed)
+}
+
+func (b *libraryBackend) RecvPtzStorePreset(inst Handle, preset int) (bool, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRecvPtzStorePreset, uintptr(inst), uintptr(preset))
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) RecvPtzRecallPreset(inst Handle, preset int, speed float32) (bool, error) {
+	proc := b.funcPtrs.NDIlibRecvPtzRecallPreset
+	if proc == 0 {
+		return false, ErrNotSupported
+	}
+
+	ret, eno := callProcIntFloat(proc, uintptr(inst), int32(preset), speed)
+	if eno != 0 {
+		return false, Error{eno}
+	}
+	return retBool(ret), nil
+}
+
+func (b *libraryBackend) RecvPtzAutoFocus(inst Handle) (bool, error) {
+	return b.callBool(b.funcPtrs.NDIlibRecvPtzAutoFocus, inst)
+}
+
+func (b *libraryBackend) RecvPtzFocus(inst Handle, focus float32) (bool, error) {
+	return b.callFloats(b.funcPtrs.NDIlibRecvPtzFocus, inst, focus)
+}
+
+func (b *libraryBackend) RecvPtzFocusSpeed(inst Handle, speed float32) (bool, error) {
+	return b.callFloats(b.funcPtrs.NDIlibRecvPtzFocusSpeed, inst, speed)
+}
+
+func (b *libraryBackend) RecvPtzWhiteBalanceAuto(inst Handle) (bool, error) {
+	return b.callBool(b.funcPtrs.NDIlibRecvPtzWhiteBalanceAuto, inst)
+}
+
+func (b *libraryBackend) RecvPtzWhiteBalanceIndoor(inst Handle) (bool, error) {
+	return b.callBool(b.funcPtrs.NDIlibRecvPtzWhiteBalanceIndoor, inst)
+}
+
+func (b *libraryBackend) RecvPtzWhiteBalanceOutdoor(inst Handle) (bool, error) {
+	return b.callBool(b.funcPtrs.NDIlibRecvPtzWhiteBalanceOutdoor, inst)
+}
+
+func (b *libraryBackend) RecvPtzWhiteBalanceOneshot(inst Handle) (bool, error) {
+	return b.callBool(b.funcPtrs.NDIlibRecvPtzWhiteBalanceOneshot, inst)
+}
+
+func (b *libraryBackend) RecvPtzWhiteBalanceManual(inst Handle, red, blue float32) (bool, error) {
+	return b.callFloats(b.funcPtrs.NDIlibRecvPtzWhiteBalanceManual, inst, red, blue)
+}
+
+func (b *libraryBackend) RecvPtzExposureAuto(inst Handle) (bool, error) {
+	return b.callBool(b.funcPtrs.NDIlibRecvPtzExposureAuto, inst)
+}
+
+func (b *libraryBackend) RecvPtzExposureManual(inst Handle, level float32) (bool, error) {
+	return b.callFloats(b.funcPtrs.NDIlibRecvPtzExposureManual, inst, level)
+}
+
+func (b *libraryBackend) RecvPtzExposureManualV2(inst Handle, iris, gain, shutterSpeed float32) (bool, error) {
+	return b.callFloats(b.funcPtrs.NDIlibRecvPtzExposureManualV2, inst, iris, gain, shutterSpeed)
+}
+
+func (b *libraryBackend) RecvRecordingIsSupported(inst Handle) (bool, error) {
+	return b.callBool(b.funcPtrs.NDIlibRecvRecordingIsSupported, inst)
+}
+
+func (b *libraryBackend) RecvRecordingStart(inst Handle, filenameHint *byte) (bool, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRecvRecordingStart, uintptr(inst), uintptr(unsafe.Pointer(filenameHint)))
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) RecvRecordingStop(inst Handle) (bool, error) {
+	return b.callBool(b.funcPtrs.NDIlibRecvRecordingStop, inst)
+}
+
+func (b *libraryBackend) RecvRecordingSetAudioLevel(inst Handle, levelDB float32) (bool, error) {
+	return b.callFloats(b.funcPtrs.NDIlibRecvRecordingSetAudioLevel, inst, levelDB)
+}
+
+func (b *libraryBackend) RecvRecordingIsRecording(inst Handle) (bool, error) {
+	return b.callBool(b.funcPtrs.NDIlibRecvRecordingIsRecording, inst)
+}
+
+func (b *libraryBackend) RecvRecordingGetTimes(inst Handle, times *RecvRecordingTime) (bool, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRecvRecordingGetTimes, uintptr(inst), uintptr(unsafe.Pointer(times)))
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) RecvRecordingGetFilename(inst Handle) (*byte, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRecvRecordingGetFilename, uintptr(inst))
+	return (*byte)(unsafe.Pointer(ret)), err
+}
+
+func (b *libraryBackend) RecvRecordingGetError(inst Handle) (*byte, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRecvRecordingGetError, uintptr(inst))
+	return (*byte)(unsafe.Pointer(ret)), err
+}
+
+func (b *libraryBackend) RecvFreeString(inst Handle, s *byte) error {
+	_, err := b.call(b.funcPtrs.NDIlibRecvFreeString, uintptr(inst), uintptr(unsafe.Pointer(s)))
+	return err
+}
+
+func (b *libraryBackend) RoutingCreate(settings *RoutingCreateSettings) (Handle, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRoutingCreate, uintptr(unsafe.Pointer(settings)))
+	return Handle(ret), err
+}
+
+func (b *libraryBackend) RoutingDestroy(inst Handle) error {
+	_, err := b.call(b.funcPtrs.NDIlibRoutingDestroy, uintptr(inst))
+	return err
+}
+
+func (b *libraryBackend) RoutingChange(inst Handle, source *Source) (bool, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRoutingChange, uintptr(inst), uintptr(unsafe.Pointer(source)))
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) RoutingClear(inst Handle) (bool, error) {
+	ret, err := b.call(b.funcPtrs.NDIlibRoutingClear, uintptr(inst))
+	return retBool(ret), err
+}
+
+func (b *libraryBackend) UtilSendSendAudioInterleaved16s(inst Handle, af *AudioFrameInterleaved16s) error {
+	_, err := b.call(b.funcPtrs.NDIlibUtilSendSendAudioInterleaved16s, uintptr(inst), uintptr(unsafe.Pointer(af)))
+	return err
+}
+
+func (b *libraryBackend) UtilSendSendAudioInterleaved32f(inst Handle, af *AudioFrameInterleaved32f) error {
+	_, err := b.call(b.funcPtrs.NDIlibUtilSendSendAudioInterleaved32f, uintptr(inst), uintptr(unsafe.Pointer(af)))
+	return err
+}
+
+func (b *libraryBackend) UtilAudioToInterleaved16sV2(src *AudioFrameV2, dst *AudioFrameInterleaved16s) error {
+	_, err := b.call(b.funcPtrs.NDIlibUtilAudioToInterleaved16sV2, uintptr(unsafe.Pointer(src)), uintptr(unsafe.Pointer(dst)))
+	return err
+}
+
+func (b *libraryBackend) UtilAudioFromInterleaved16sV2(src *AudioFrameInterleaved16s, dst *AudioFrameV2) error {
+	_, err := b.call(b.funcPtrs.NDIlibUtilAudioFromInterleaved16sV2, uintptr(unsafe.Pointer(src)), uintptr(unsafe.Pointer(dst)))
+	return err
+}
+
+func (b *libraryBackend) UtilAudioToInterleaved32fV2(src *AudioFrameV2, dst *AudioFrameInterleaved32f) error {
+	_, err := b.call(b.funcPtrs.NDIlibUtilAudioToInterleaved32fV2, uintptr(unsafe.Pointer(src)), uintptr(unsafe.Pointer(dst)))
+	return err
+}
+
+func (b *libraryBackend) UtilAudioFromInterleaved32fV2(src *AudioFrameInterleaved32f, dst *AudioFrameV2) error {
+	_, err := b.call(b.funcPtrs.NDIlibUtilAudioFromInterleaved32fV2, uintptr(unsafe.Pointer(src)), uintptr(unsafe.Pointer(dst)))
+	return err
+}
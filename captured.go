@@ -0,0 +1,219 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var alreadyReleasedErr = errors.New("frame was already released")
+
+//Counts the references to a captured frame, which starts out with one.
+type frameRefs struct {
+	refs atomic.Int32
+}
+
+//Adds a reference, it fails when the last one was already released.
+func (r *frameRefs) retain() error {
+	for {
+		n := r.refs.Load()
+		if n <= 0 {
+			return alreadyReleasedErr
+		}
+		if r.refs.CompareAndSwap(n, n+1) {
+			return nil
+		}
+	}
+}
+
+//Drops a reference and reports whether it was the last one.
+func (r *frameRefs) release() (bool, error) {
+	for {
+		n := r.refs.Load()
+		if n <= 0 {
+			return false, alreadyReleasedErr
+		}
+		if r.refs.CompareAndSwap(n, n-1) {
+			return n == 1, nil
+		}
+	}
+}
+
+//Fails when the last reference was already released.
+func (r *frameRefs) check() error {
+	if r.refs.Load() <= 0 {
+		return alreadyReleasedErr
+	}
+	return nil
+}
+
+//CapturedVideoFrame is a video frame filled in by CaptureV2 that remembers the receive instance it has to be freed on.
+//Every Retain must be matched by a Release, the frame is freed when the last reference is released. Using the frame
+//after that returns an error.
+type CapturedVideoFrame struct {
+	frameRefs
+	recv  *RecvInstance
+	frame VideoFrameV2
+}
+
+//NewCapturedVideoFrame takes over a frame that CaptureV2 filled in on recv, holding one reference to it. The frame
+//pointed to by vf can be captured into again right away.
+func NewCapturedVideoFrame(recv *RecvInstance, vf *VideoFrameV2) *CapturedVideoFrame {
+	f := &CapturedVideoFrame{recv: recv, frame: *vf}
+	f.refs.Store(1)
+	trackLeak(f)
+	return f
+}
+
+//Frame returns the frame, which stays valid until the last reference is released.
+func (f *CapturedVideoFrame) Frame() (*VideoFrameV2, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	return &f.frame, nil
+}
+
+//Retain adds a reference, for handing the frame to another consumer.
+func (f *CapturedVideoFrame) Retain() (*CapturedVideoFrame, error) {
+	if err := f.retain(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+//Release drops a reference and frees the frame if it was the last one.
+func (f *CapturedVideoFrame) Release() error {
+	last, err := f.release()
+	if !last {
+		return err
+	}
+
+	untrackLeak(f)
+	return f.recv.FreeVideoV2(&f.frame)
+}
+
+//Close is Release, it makes the frame an io.Closer that can be registered with an ObjectPool.
+func (f *CapturedVideoFrame) Close() error {
+	return f.Release()
+}
+
+//Clone returns a copy of the frame that Go owns and that outlives it.
+func (f *CapturedVideoFrame) Clone() (*VideoFrameV2, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	return cloneVideoFrameV2(&f.frame), nil
+}
+
+//CapturedAudioFrame is an audio frame filled in by CaptureV2, see CapturedVideoFrame.
+type CapturedAudioFrame struct {
+	frameRefs
+	recv  *RecvInstance
+	frame AudioFrameV2
+}
+
+//NewCapturedAudioFrame takes over a frame that CaptureV2 filled in on recv, holding one reference to it.
+func NewCapturedAudioFrame(recv *RecvInstance, af *AudioFrameV2) *CapturedAudioFrame {
+	f := &CapturedAudioFrame{recv: recv, frame: *af}
+	f.refs.Store(1)
+	trackLeak(f)
+	return f
+}
+
+//Frame returns the frame, which stays valid until the last reference is released.
+func (f *CapturedAudioFrame) Frame() (*AudioFrameV2, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	return &f.frame, nil
+}
+
+//Retain adds a reference, for handing the frame to another consumer.
+func (f *CapturedAudioFrame) Retain() (*CapturedAudioFrame, error) {
+	if err := f.retain(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+//Release drops a reference and frees the frame if it was the last one.
+func (f *CapturedAudioFrame) Release() error {
+	last, err := f.release()
+	if !last {
+		return err
+	}
+
+	untrackLeak(f)
+	return f.recv.FreeAudioV2(&f.frame)
+}
+
+//Close is Release, it makes the frame an io.Closer that can be registered with an ObjectPool.
+func (f *CapturedAudioFrame) Close() error {
+	return f.Release()
+}
+
+//Clone returns a copy of the frame that Go owns and that outlives it.
+func (f *CapturedAudioFrame) Clone() (*AudioFrameV2, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	return cloneAudioFrameV2(&f.frame), nil
+}
+
+//CapturedMetadata is a metadata frame filled in by CaptureV2, see CapturedVideoFrame.
+type CapturedMetadata struct {
+	frameRefs
+	recv  *RecvInstance
+	frame MetadataFrame
+}
+
+//NewCapturedMetadata takes over a frame that CaptureV2 filled in on recv, holding one reference to it.
+func NewCapturedMetadata(recv *RecvInstance, mf *MetadataFrame) *CapturedMetadata {
+	f := &CapturedMetadata{recv: recv, frame: *mf}
+	f.refs.Store(1)
+	trackLeak(f)
+	return f
+}
+
+//Frame returns the frame, which stays valid until the last reference is released.
+func (f *CapturedMetadata) Frame() (*MetadataFrame, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	return &f.frame, nil
+}
+
+//Retain adds a reference, for handing the frame to another consumer.
+func (f *CapturedMetadata) Retain() (*CapturedMetadata, error) {
+	if err := f.retain(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+//Release drops a reference and frees the frame if it was the last one.
+func (f *CapturedMetadata) Release() error {
+	last, err := f.release()
+	if !last {
+		return err
+	}
+
+	untrackLeak(f)
+	return f.recv.FreeMetadataV2(&f.frame)
+}
+
+//Close is Release, it makes the frame an io.Closer that can be registered with an ObjectPool.
+func (f *CapturedMetadata) Close() error {
+	return f.Release()
+}
+
+//Clone returns a copy of the frame that Go owns and that outlives it.
+func (f *CapturedMetadata) Clone() (*MetadataFrame, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	return cloneMetadataFrame(&f.frame), nil
+}
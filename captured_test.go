@@ -0,0 +1,92 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCapturedFrames(t *testing.T) {
+	fake, pool, recv := withFakeRecv(t, "CAMERA (1)")
+
+	videoData := []byte{1, 2, 3, 4}
+	fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeVideo, Video: &VideoFrameV2{Xres: 1, Yres: 1, FourCC: FourCCTypeBGRA, Data: &videoData[0]}})
+	fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeAudio, Audio: NewAudioFrameV2()})
+	fake.Deliver("CAMERA (1)", fakeMetadata("<hello/>"))
+
+	var (
+		vf VideoFrameV2
+		af AudioFrameV2
+		mf MetadataFrame
+	)
+	if ft := must(recv.CaptureV2(&vf, nil, nil, 1000)); ft != FrameTypeVideo {
+		t.Fatalf("Expected a video frame, got %d.", ft)
+	}
+	video := NewCapturedVideoFrame(recv, &vf)
+	if ft := must(recv.CaptureV2(nil, &af, nil, 1000)); ft != FrameTypeAudio {
+		t.Fatalf("Expected an audio frame, got %d.", ft)
+	}
+	audio := NewCapturedAudioFrame(recv, &af)
+	if ft := must(recv.CaptureV2(nil, nil, &mf, 1000)); ft != FrameTypeMetadata {
+		t.Fatalf("Expected a metadata frame, got %d.", ft)
+	}
+	metadata := NewCapturedMetadata(recv, &mf)
+
+	//Freeing a frame on a receiver that did not capture it fails and leaves it held.
+	other := newFakeRecv(t, fake, pool, "CAMERA (2)")
+	if err := other.FreeVideoV2(&vf); !errors.Is(err, notCapturedErr) {
+		t.Errorf("Expected freeing on another receiver to fail, got %v.", err)
+	}
+
+	clone := must(video.Clone())
+	if *clone.Data != 1 || clone.Data == must(video.Frame()).Data {
+		t.Error("Expected the clone to own a copy of the data.")
+	}
+	if s := goStringFromPtr(must(metadata.Clone()).Data); s != "<hello/>" {
+		t.Errorf("Unexpected metadata clone %q.", s)
+	}
+
+	//Fan the video frame out to several consumers, it is freed by the last one.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(f *CapturedVideoFrame) {
+			defer wg.Done()
+			if err := f.Release(); err != nil {
+				t.Error(err)
+			}
+		}(must(video.Retain()))
+	}
+	wg.Wait()
+	if n := fake.CapturedFrames(); n != 3 {
+		t.Fatalf("Expected the frames to still be held, %d are.", n)
+	}
+
+	checkErr(t, video.Release())
+	checkErr(t, audio.Release())
+	checkErr(t, metadata.Close())
+	if n := fake.CapturedFrames(); n != 0 {
+		t.Fatalf("Expected every frame to be freed, %d are not.", n)
+	}
+
+	if err := video.Release(); err == nil {
+		t.Error("Expected releasing a released frame to fail.")
+	}
+	if n := fake.CapturedFrames(); n != 0 {
+		t.Fatalf("Expected the frame not to be freed twice, got %d.", n)
+	}
+
+	if _, err := audio.Retain(); !errors.Is(err, alreadyReleasedErr) {
+		t.Errorf("Expected retaining a released frame to fail, got %v.", err)
+	}
+	if _, err := audio.Frame(); !errors.Is(err, alreadyReleasedErr) {
+		t.Errorf("Expected using a released frame to fail, got %v.", err)
+	}
+	if _, err := metadata.Clone(); !errors.Is(err, alreadyReleasedErr) {
+		t.Errorf("Expected cloning a released frame to fail, got %v.", err)
+	}
+}
@@ -0,0 +1,128 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var invalidMetadataErr = errors.New("invalid metadata")
+
+//The connection metadata frames handed to the runtime for an instance. The runtime reads them again on every new
+//connection, so they are kept reachable from Go until they are cleared or the instance is destroyed.
+type connectionMetadata struct {
+	mu     sync.Mutex
+	frames []*MetadataFrame
+}
+
+//Hands a copy of mf to the runtime with add and keeps the copy. The library must be locked for reading.
+func (c *connectionMetadata) add(mf *MetadataFrame, add func(mf *MetadataFrame) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	mf = cloneMetadataFrame(mf)
+	if err := add(mf); err != nil {
+		return err
+	}
+	c.frames = append(c.frames, mf)
+	return nil
+}
+
+//Clears the frames in the runtime with clear and drops them. The library must be locked for reading.
+func (c *connectionMetadata) clear(clear func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := clear(); err != nil {
+		return err
+	}
+	c.frames = nil
+	return nil
+}
+
+//Drops the frames once the runtime no longer uses them because the instance was destroyed.
+func (c *connectionMetadata) reset() {
+	c.mu.Lock()
+	c.frames = nil
+	c.mu.Unlock()
+}
+
+//Returns a metadata frame holding s, which must be a well-formed UTF-8 XML string without NULL characters.
+func metadataFrameFromString(s string) (*MetadataFrame, error) {
+	if s == "" {
+		return nil, fmt.Errorf("%w: metadata is empty", invalidMetadataErr)
+	}
+
+	if !utf8.ValidString(s) {
+		return nil, fmt.Errorf("%w: metadata is not valid UTF-8", invalidMetadataErr)
+	}
+
+	if strings.IndexByte(s, 0) >= 0 {
+		return nil, fmt.Errorf("%w: metadata contains a NULL character", invalidMetadataErr)
+	}
+
+	if err := checkXMLDocument(s); err != nil {
+		return nil, err
+	}
+
+	mf := NewMetadataFrame()
+	mf.Data = cStringPtr(s)
+	return mf, nil
+}
+
+//Checks that s is a well-formed XML document: exactly one root element, with only white space, comments and processing
+//instructions outside of it.
+func checkXMLDocument(s string) error {
+	d := xml.NewDecoder(strings.NewReader(s))
+	depth, roots := 0, 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", invalidMetadataErr, err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(tok)) > 0 {
+				return fmt.Errorf("%w: metadata has text outside of its root element", invalidMetadataErr)
+			}
+		case xml.Directive:
+			if depth == 0 {
+				return fmt.Errorf("%w: metadata has a directive outside of its root element", invalidMetadataErr)
+			}
+		}
+	}
+
+	if roots != 1 {
+		return fmt.Errorf("%w: metadata has %d root elements instead of one", invalidMetadataErr, roots)
+	}
+	return nil
+}
+
+//Returns a metadata frame holding the XML encoding of v, see encoding/xml.
+func metadataFrameFromXML(v any) (*MetadataFrame, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return metadataFrameFromString(string(data))
+}
@@ -0,0 +1,58 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testReceiverIdentity struct {
+	XMLName xml.Name `xml:"ndi_receiver"`
+	Product string   `xml:"product,attr"`
+}
+
+func TestConnectionMetadata(t *testing.T) {
+	fake, pool := withFake(t)
+
+	send := must(NewSendInstance(pool.NewSendCreateSettings("camera", "", true, false)))
+	pool.Register(send)
+	recv := newFakeRecv(t, fake, pool, "FAKE (camera)")
+
+	//The runtime reads the frame again later, reusing the buffer must not change what it sees.
+	data := []byte("<ndi_format/>\x00")
+	checkErr(t, recv.AddConnectionMetadata(&MetadataFrame{Data: &data[0]}))
+	copy(data, "<ndi_broken/>")
+	checkErr(t, recv.AddConnectionMetadataXML(testReceiverIdentity{Product: "panel"}))
+
+	if m := fake.ConnectionMetadata("FAKE (camera)"); !reflect.DeepEqual(m, []string{"<ndi_format/>", `<ndi_receiver product="panel"></ndi_receiver>`}) {
+		t.Errorf("Unexpected receiver connection metadata %q.", m)
+	}
+
+	checkErr(t, send.AddConnectionMetadataString(`<ndi_product long_name="Camera"/>`))
+	if m := fake.SenderConnectionMetadata("FAKE (camera)"); !reflect.DeepEqual(m, []string{`<ndi_product long_name="Camera"/>`}) {
+		t.Errorf("Unexpected sender connection metadata %q.", m)
+	}
+
+	checkErr(t, send.AddConnectionMetadataString("<?xml version=\"1.0\"?>\n<!-- identity -->\n<ndi_product/>\n"))
+	checkErr(t, send.ClearConnectionMetadata())
+
+	for _, s := range []string{"", "<a>", "<a/>\x00", "\xff", "hello", "<a/><b/>", "<a/>tail"} {
+		if err := send.AddConnectionMetadataString(s); !errors.Is(err, invalidMetadataErr) {
+			t.Errorf("Expected %q to be rejected, got %v.", s, err)
+		}
+	}
+
+	checkErr(t, recv.ClearConnectionMetadata())
+	checkErr(t, send.ClearConnectionMetadata())
+	if m := fake.ConnectionMetadata("FAKE (camera)"); len(m) != 0 {
+		t.Errorf("Expected no receiver connection metadata after clearing, got %q.", m)
+	}
+	if m := fake.SenderConnectionMetadata("FAKE (camera)"); len(m) != 0 {
+		t.Errorf("Expected no sender connection metadata after clearing, got %q.", m)
+	}
+}
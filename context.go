@@ -0,0 +1,64 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+//The longest single call into the runtime made by the context-aware functions, this bounds how long it takes them to
+//notice that their context is done.
+const contextWaitSlice = 50 * time.Millisecond
+
+//Rounds d up to whole milliseconds for the runtime.
+func durationToMs(d time.Duration) uint32 {
+	if d <= 0 {
+		return 0
+	}
+
+	ms := (d + time.Millisecond - 1) / time.Millisecond
+	if ms > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(ms)
+}
+
+//Repeats call with timeouts of at most contextWaitSlice until it reports that it is done, fails, the timeout elapses
+//or ctx is done. Calls that return early without being done are spaced out so that runtime functions which do not
+//block themselves are polled rather than spun on. The last value returned by call is returned on timeout, ctx.Err()
+//when ctx is done first.
+func waitContext[T any](ctx context.Context, timeout time.Duration, call func(timeoutInMs uint32) (T, bool, error)) (T, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+
+		slice := min(time.Until(deadline), contextWaitSlice)
+		if d, ok := ctx.Deadline(); ok {
+			slice = min(slice, time.Until(d))
+		}
+
+		start := time.Now()
+		v, done, err := call(durationToMs(slice))
+		if err != nil || done || !time.Now().Before(deadline) {
+			return v, err
+		}
+
+		if rest := slice - time.Since(start); rest > 0 {
+			t := time.NewTimer(rest)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				var zero T
+				return zero, ctx.Err()
+			case <-t.C:
+			}
+		}
+	}
+}
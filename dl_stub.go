@@ -0,0 +1,40 @@
+//go:build !windows && !((linux || darwin) && cgo)
+
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"fmt"
+	"syscall"
+)
+
+var unsupportedPlatformErr = fmt.Errorf("loading the ndi runtime on this platform: %w", ErrNotSupported)
+
+type libHandle = uintptr
+
+func openLibrary(path string) (libHandle, error) {
+	return 0, unsupportedPlatformErr
+}
+
+func lookupProc(lib libHandle, name string) (uintptr, error) {
+	return 0, unsupportedPlatformErr
+}
+
+func closeLibrary(lib libHandle) error {
+	return unsupportedPlatformErr
+}
+
+func callProc(proc uintptr, args ...uintptr) (uintptr, syscall.Errno) {
+	return 0, syscall.ENOSYS
+}
+
+func callProcFloats(proc, inst uintptr, args ...float32) (uintptr, syscall.Errno) {
+	return 0, syscall.ENOSYS
+}
+
+func callProcIntFloat(proc, inst uintptr, i int32, f float32) (uintptr, syscall.Errno) {
+	return 0, syscall.ENOSYS
+}
@@ -0,0 +1,187 @@
+//go:build (linux || darwin) && cgo
+
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+/*
+#cgo linux LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef uintptr_t (*ndi_proc0)(void);
+typedef uintptr_t (*ndi_proc1)(uintptr_t);
+typedef uintptr_t (*ndi_proc2)(uintptr_t, uintptr_t);
+typedef uintptr_t (*ndi_proc3)(uintptr_t, uintptr_t, uintptr_t);
+typedef uintptr_t (*ndi_proc4)(uintptr_t, uintptr_t, uintptr_t, uintptr_t);
+typedef uintptr_t (*ndi_proc5)(uintptr_t, uintptr_t, uintptr_t, uintptr_t, uintptr_t);
+typedef uintptr_t (*ndi_proc6)(uintptr_t, uintptr_t, uintptr_t, uintptr_t, uintptr_t, uintptr_t);
+
+typedef bool (*ndi_proc_f1)(uintptr_t, float);
+typedef bool (*ndi_proc_f2)(uintptr_t, float, float);
+typedef bool (*ndi_proc_f3)(uintptr_t, float, float, float);
+typedef bool (*ndi_proc_if)(uintptr_t, int, float);
+
+// The dlerror state is per thread and the goroutine may move to another thread between two cgo calls, so the shims
+// copy the error into *err right after the call that failed. The copy must be freed.
+static char* ndi_dlerror(void) {
+	const char* msg = dlerror();
+	return msg != NULL ? strdup(msg) : NULL;
+}
+
+static uintptr_t ndi_dlopen(const char* path, char** err) {
+	dlerror();
+	void* lib = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (lib == NULL) {
+		*err = ndi_dlerror();
+	}
+	return (uintptr_t)lib;
+}
+
+static uintptr_t ndi_dlsym(uintptr_t lib, const char* name, char** err) {
+	dlerror();
+	void* proc = dlsym((void*)lib, name);
+	if (proc == NULL) {
+		*err = ndi_dlerror();
+	}
+	return (uintptr_t)proc;
+}
+
+static int ndi_dlclose(uintptr_t lib, char** err) {
+	dlerror();
+	int ret = dlclose((void*)lib);
+	if (ret != 0) {
+		*err = ndi_dlerror();
+	}
+	return ret;
+}
+
+static uintptr_t ndi_call(uintptr_t proc, int n, uintptr_t a0, uintptr_t a1, uintptr_t a2, uintptr_t a3, uintptr_t a4, uintptr_t a5) {
+	switch (n) {
+	case 0: return ((ndi_proc0)proc)();
+	case 1: return ((ndi_proc1)proc)(a0);
+	case 2: return ((ndi_proc2)proc)(a0, a1);
+	case 3: return ((ndi_proc3)proc)(a0, a1, a2);
+	case 4: return ((ndi_proc4)proc)(a0, a1, a2, a3);
+	case 5: return ((ndi_proc5)proc)(a0, a1, a2, a3, a4);
+	default: return ((ndi_proc6)proc)(a0, a1, a2, a3, a4, a5);
+	}
+}
+
+static uintptr_t ndi_call_floats(uintptr_t proc, uintptr_t inst, int n, float f0, float f1, float f2) {
+	switch (n) {
+	case 1: return ((ndi_proc_f1)proc)(inst, f0);
+	case 2: return ((ndi_proc_f2)proc)(inst, f0, f1);
+	default: return ((ndi_proc_f3)proc)(inst, f0, f1, f2);
+	}
+}
+
+static uintptr_t ndi_call_int_float(uintptr_t proc, uintptr_t inst, int i, float f) {
+	return ((ndi_proc_if)proc)(inst, i, f);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	maxProcArgs      = 6
+	maxProcFloatArgs = 3
+)
+
+type libHandle = uintptr
+
+//Returns the error a shim copied from dlerror and frees the copy, or an error built from fallback when there was none.
+func dlError(msg *C.char, fallback string) error {
+	if msg == nil {
+		return errors.New(fallback)
+	}
+	defer C.free(unsafe.Pointer(msg))
+	return errors.New(C.GoString(msg))
+}
+
+func openLibrary(path string) (libHandle, error) {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	var msg *C.char
+	lib := libHandle(C.ndi_dlopen(cPath, &msg))
+	if lib == 0 {
+		return 0, dlError(msg, "unable to open "+path)
+	}
+	return lib, nil
+}
+
+func lookupProc(lib libHandle, name string) (uintptr, error) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	var msg *C.char
+	proc := uintptr(C.ndi_dlsym(C.uintptr_t(lib), cName, &msg))
+	if proc == 0 {
+		return 0, dlError(msg, "unable to find "+name)
+	}
+	return proc, nil
+}
+
+func closeLibrary(lib libHandle) error {
+	var msg *C.char
+	if C.ndi_dlclose(C.uintptr_t(lib), &msg) != 0 {
+		return dlError(msg, "unable to close library")
+	}
+	return nil
+}
+
+//Calls a function exported by the runtime. Pointers passed as arguments are kept alive for the duration of the call.
+//
+//go:uintptrescapes
+func callProc(proc uintptr, args ...uintptr) (uintptr, syscall.Errno) {
+	if len(args) > maxProcArgs {
+		panic("ndi: too many arguments to runtime function")
+	}
+
+	var a [maxProcArgs]uintptr
+	copy(a[:], args)
+
+	ret := C.ndi_call(
+		C.uintptr_t(proc),
+		C.int(len(args)),
+		C.uintptr_t(a[0]),
+		C.uintptr_t(a[1]),
+		C.uintptr_t(a[2]),
+		C.uintptr_t(a[3]),
+		C.uintptr_t(a[4]),
+		C.uintptr_t(a[5]),
+	)
+	return uintptr(ret), 0
+}
+
+//Calls a function exported by the runtime that takes an instance followed by between one and three floats. Floats are
+//passed in other registers than integers, so they cannot go through callProc.
+func callProcFloats(proc, inst uintptr, args ...float32) (uintptr, syscall.Errno) {
+	if len(args) == 0 || len(args) > maxProcFloatArgs {
+		panic("ndi: wrong number of float arguments to runtime function")
+	}
+
+	var a [maxProcFloatArgs]float32
+	copy(a[:], args)
+
+	ret := C.ndi_call_floats(C.uintptr_t(proc), C.uintptr_t(inst), C.int(len(args)), C.float(a[0]), C.float(a[1]), C.float(a[2]))
+	return uintptr(ret), 0
+}
+
+//Calls a function exported by the runtime that takes an instance, an int and a float.
+func callProcIntFloat(proc, inst uintptr, i int32, f float32) (uintptr, syscall.Errno) {
+	ret := C.ndi_call_int_float(C.uintptr_t(proc), C.uintptr_t(inst), C.int(i), C.float(f))
+	return uintptr(ret), 0
+}
@@ -0,0 +1,26 @@
+//go:build (linux || darwin) && cgo
+
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenLibraryError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "libmissing.so")
+	_, err := openLibrary(path)
+	if err == nil {
+		t.Fatal("Opening a missing library did not fail.")
+	}
+
+	//The message comes from dlerror and names the library, it is not the fallback.
+	if msg := err.Error(); msg == "unable to open "+path || !strings.Contains(msg, path) {
+		t.Errorf("Expected the dlerror message, got %q.", msg)
+	}
+}
@@ -0,0 +1,60 @@
+//go:build windows
+
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"math"
+	"runtime"
+	"syscall"
+)
+
+type libHandle = syscall.Handle
+
+func openLibrary(path string) (libHandle, error) {
+	return syscall.LoadLibrary(path)
+}
+
+func lookupProc(lib libHandle, name string) (uintptr, error) {
+	return syscall.GetProcAddress(lib, name)
+}
+
+func closeLibrary(lib libHandle) error {
+	return syscall.FreeLibrary(lib)
+}
+
+//Calls a function exported by the runtime. Pointers passed as arguments are kept alive for the duration of the call.
+//
+//go:uintptrescapes
+func callProc(proc uintptr, args ...uintptr) (uintptr, syscall.Errno) {
+	ret, _, eno := syscall.SyscallN(proc, args...)
+	return ret, eno
+}
+
+//On amd64 the system call trampoline copies the first four arguments into the floating point registers as well, and on
+//386 floats are pushed on the stack like 32 bit integers, so floats can be passed as their bits. Elsewhere they cannot.
+const floatArgsAsBits = runtime.GOARCH == "amd64" || runtime.GOARCH == "386"
+
+//Calls a function exported by the runtime that takes an instance followed by between one and three floats.
+func callProcFloats(proc, inst uintptr, args ...float32) (uintptr, syscall.Errno) {
+	if !floatArgsAsBits {
+		return 0, syscall.EWINDOWS
+	}
+
+	a := []uintptr{inst}
+	for _, f := range args {
+		a = append(a, uintptr(math.Float32bits(f)))
+	}
+	return callProc(proc, a...)
+}
+
+//Calls a function exported by the runtime that takes an instance, an int and a float.
+func callProcIntFloat(proc, inst uintptr, i int32, f float32) (uintptr, syscall.Errno) {
+	if !floatArgsAsBits {
+		return 0, syscall.EWINDOWS
+	}
+	return callProc(proc, inst, uintptr(i), uintptr(math.Float32bits(f)))
+}
@@ -5,26 +5,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
-	"path"
+	"os/signal"
 
 	"github.com/diskett-io/ndi-go"
 )
 
-const (
-	ndiLibName  = "Processing.NDI.Lib.x64.dll"
-	scanTimeout = 5000
-)
-
 func initializeNDI() {
-	libDir := os.Getenv("NDI_RUNTIME_DIR_V3")
-	if libDir == "" {
-		log.Fatalln("ndi sdk is not installed")
-	}
-
-	if err := ndi.LoadAndInitialize(path.Join(libDir, ndiLibName)); err != nil {
+	if err := ndi.LoadDefault(); err != nil {
 		log.Fatalln(err)
 	}
 }
@@ -34,9 +25,9 @@ func main() {
 
 	pool := ndi.NewObjectPool()
 	settings := pool.NewFindCreateSettings(true, "", "")
-	inst := ndi.NewFindInstanceV2(settings)
-	if inst == nil {
-		log.Fatalln("could not create finder")
+	inst, err := ndi.NewFindInstanceV2(settings)
+	if err != nil {
+		log.Fatalln("could not create finder:", err)
 	}
 
 	defer func() {
@@ -46,37 +37,24 @@ func main() {
 
 	fmt.Println("Searching for NDI sources...")
 
-	currentSources := make(map[string]string)
-	for {
-		inst.WaitForSources(scanTimeout)
-		sources := inst.GetCurrentSources()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-		var newListing bool
-		if len(currentSources) != len(sources) {
-			newListing = true
-		} else {
-			for _, source := range sources {
-				name := source.Name()
-				addr := source.Address()
+	watcher, err := ndi.NewWatcher(ctx, inst)
+	if err != nil {
+		log.Fatalln("could not watch sources:", err)
+	}
 
-				if n, ok := currentSources[addr]; !ok || n != name {
-					newListing = true
-					break
-				}
-			}
+	for ev := range watcher.Events() {
+		switch ev.Type {
+		case ndi.SourceChanged:
+			fmt.Printf("Changed: %s, %s (was %s, %s)\n", ev.Source.Name, ev.Source.Address, ev.Previous.Name, ev.Previous.Address)
+		default:
+			fmt.Printf("%s: %s, %s\n", ev.Type, ev.Source.Name, ev.Source.Address)
 		}
+	}
 
-		if newListing {
-			fmt.Printf("%d available source(s):\n", len(sources))
-			currentSources = make(map[string]string)
-
-			for _, source := range sources {
-				name := source.Name()
-				addr := source.Address()
-
-				currentSources[addr] = name
-				fmt.Printf("Name: %s, Address: %s\n", name, addr)
-			}
-		}
+	if err := watcher.Wait(); err != nil && err != context.Canceled {
+		log.Println(err)
 	}
 }
@@ -7,24 +7,14 @@ package main
 import (
 	"fmt"
 	"log"
-	"os"
-	"path"
 
 	"github.com/diskett-io/ndi-go"
 )
 
-const (
-	ndiLibName    = "Processing.NDI.Lib.x64.dll"
-	ndiSourceName = "ndi-go test"
-)
+const ndiSourceName = "ndi-go test"
 
 func initializeNDI() {
-	libDir := os.Getenv("NDI_RUNTIME_DIR_V3")
-	if libDir == "" {
-		log.Fatalln("ndi sdk is not installed")
-	}
-
-	if err := ndi.LoadAndInitialize(path.Join(libDir, ndiLibName)); err != nil {
+	if err := ndi.LoadDefault(); err != nil {
 		log.Fatalln(err)
 	}
 }
@@ -35,9 +25,9 @@ func main() {
 
 	pool := ndi.NewObjectPool()
 	findSettings := pool.NewFindCreateSettings(true, "", "")
-	findInst := ndi.NewFindInstanceV2(findSettings)
-	if findInst == nil {
-		log.Fatalln("could not create finder")
+	findInst, err := ndi.NewFindInstanceV2(findSettings)
+	if err != nil {
+		log.Fatalln("could not create finder:", err)
 	}
 
 	var recvInst *ndi.RecvInstance
@@ -45,25 +35,33 @@ func main() {
 	fmt.Println("Searching for NDI sources...")
 
 	for recvInst == nil {
-		for _, source := range findInst.GetCurrentSources() {
-			name := source.Name()
+		if _, err := findInst.WaitForSources(1000); err != nil {
+			log.Fatalln(err)
+		}
+
+		sources, err := findInst.CurrentSources()
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		for _, source := range sources {
+			name := source.Name
 
 			if name == ndiSourceName {
-				addr := source.Address()
+				addr := source.Address
 				recvSettings := ndi.NewRecvCreateSettings()
-				recvSettings.SourceToConnectTo = *source
+				recvSettings.SourceToConnectTo = *source.Source()
 
-				recvInst = ndi.NewRecvInstanceV2(recvSettings)
-
-				if recvInst == nil {
-					log.Printf("unable to connect to %s, %s\n", name, addr)
+				recvInst, err = ndi.NewRecvInstanceV2(recvSettings)
+				if err != nil {
+					log.Printf("unable to connect to %s, %s: %v\n", name, addr, err)
 					continue
 				}
 
 				fmt.Printf("Connected to %s, %s\n", name, addr)
 
 				findInst.Destroy()
-				pool.Release(findSettings)
+				findSettings.Close()
 				break
 			}
 		}
@@ -71,12 +69,19 @@ func main() {
 
 	defer recvInst.Destroy()
 
-	if !recvInst.SetTally(&ndi.Tally{OnProgram: true, OnPreview: true}) {
-		log.Println("could not set tally")
+	if err := recvInst.SetTally(&ndi.Tally{OnProgram: true, OnPreview: true}); err != nil {
+		log.Println("could not set tally:", err)
 	}
 
-	for recvInst.GetNumConnections(1000) == 0 {
-		fmt.Println("connections..", recvInst.GetNumConnections(1000))
+	for {
+		n, err := recvInst.GetNumConnections(1000)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if n > 0 {
+			break
+		}
+		fmt.Println("connections..", n)
 	}
 
 	fmt.Println("Reading video...")
@@ -92,7 +97,11 @@ func main() {
 		af.SetDefault()
 		mf.SetDefault()
 
-		ft, _ := recvInst.CaptureV2(nil, nil, nil, 1000)
+		ft, err := recvInst.CaptureV2(&vf, &af, &mf, 1000)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
 		switch ft {
 		case ndi.FrameTypeNone:
 			fmt.Println("FrameTypeNone")
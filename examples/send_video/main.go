@@ -7,21 +7,12 @@ package main
 import (
 	"crypto/rand"
 	"log"
-	"os"
-	"path"
 
 	"github.com/diskett-io/ndi-go"
 )
 
-const ndiLibName = "Processing.NDI.Lib.x64.dll"
-
 func initializeNDI() {
-	libDir := os.Getenv("NDI_RUNTIME_DIR_V3")
-	if libDir == "" {
-		log.Fatalln("ndi sdk is not installed")
-	}
-
-	if err := ndi.LoadAndInitialize(path.Join(libDir, ndiLibName)); err != nil {
+	if err := ndi.LoadDefault(); err != nil {
 		log.Fatalln(err)
 	}
 }
@@ -30,10 +21,14 @@ func main() {
 	initializeNDI()
 
 	pool := ndi.NewObjectPool()
-	settings := pool.NewSendCreateSettings("ndi-go test", "", true, false)
-	inst := ndi.NewSendInstance(settings)
-	if inst == nil {
-		log.Fatalln("could not create sender")
+	settings, err := pool.BuildSendCreateSettings("ndi-go test", ndi.SendClockAudio(false))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	inst, err := ndi.NewSendInstance(settings)
+	if err != nil {
+		log.Fatalln("could not create sender:", err)
 	}
 
 	frame := ndi.NewVideoFrameV2()
@@ -58,6 +53,8 @@ func main() {
 			log.Fatalln(err)
 		}
 
-		inst.SendVideoV2(frame)
+		if err := inst.SendVideoV2(frame); err != nil {
+			log.Fatalln(err)
+		}
 	}
 }
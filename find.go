@@ -5,8 +5,8 @@
 package ndi
 
 import (
-	"syscall"
-	"unsafe"
+	"context"
+	"time"
 )
 
 type Source struct {
@@ -14,56 +14,116 @@ type Source struct {
 }
 
 func (s *Source) Name() string {
-	if s.name == nil {
-		return ""
-	}
-	return goStringFromCString(uintptr(unsafe.Pointer(s.name)))
+	return goStringFromPtr(s.name)
 }
 
 func (s *Source) Address() string {
-	if s.address == nil {
-		return ""
-	}
-	return goStringFromCString(uintptr(unsafe.Pointer(s.address)))
+	return goStringFromPtr(s.address)
+}
+
+//Returns a copy of the source that does not refer to memory owned by the runtime.
+func (s *Source) Info() SourceInfo {
+	return SourceInfo{s.Name(), s.Address()}
+}
+
+//SourceInfo is a source copied out of the runtime, it stays valid after the finder that found it is destroyed.
+type SourceInfo struct {
+	Name, Address string
 }
 
-type FindInstance struct{}
+//Returns the source in the layout the runtime expects, for example for RecvCreateSettings.SourceToConnectTo. The
+//strings are allocated by Go and kept alive by the returned value and every copy of it. An empty address leaves the
+//runtime to look the source up by name.
+func (si SourceInfo) Source() *Source {
+	return &Source{cStringPtr(si.Name), cStringPtr(si.Address)}
+}
+
+type FindInstance struct {
+	instance
+}
+
+func NewFindInstanceV2(settings *FindCreateSettings) (*FindInstance, error) {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return nil, err
+	}
+	defer l.mu.RUnlock()
+
+	h, err := b.FindCreateV2(settings)
+	if err != nil {
+		return nil, err
+	}
 
-func NewFindInstanceV2(settings *FindCreateSettings) *FindInstance {
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibFindCreateV2, 1, uintptr(unsafe.Pointer(settings)), 0, 0)
-	if eno != 0 {
-		panic(eno)
+	if h == 0 {
+		return nil, ErrCreateFailed
 	}
-	return (*FindInstance)(unsafe.Pointer(ret))
+
+	inst := &FindInstance{instance: instance{lib: l, handle: h}}
+	trackLeak(inst)
+	return inst, nil
 }
 
-func (inst *FindInstance) Destroy() {
-	if _, _, eno := syscall.Syscall(funcPtrs.NDIlibFindDestroy, 1, uintptr(unsafe.Pointer(inst)), 0, 0); eno != 0 {
-		panic(eno)
+func (inst *FindInstance) Destroy() error {
+	untrackLeak(inst)
+
+	b, err := inst.lockClose()
+	if b == nil {
+		return err
 	}
+	defer inst.unlockClose()
+	return b.FindDestroy(inst.handle)
+}
+
+//Close is Destroy, it makes the instance an io.Closer that can be registered with an ObjectPool.
+func (inst *FindInstance) Close() error {
+	return inst.Destroy()
 }
 
 //This will allow you to wait until the number of online sources have changed.
-func (inst *FindInstance) WaitForSources(timeoutInMs uint32) (int, error) {
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibFindWaitForSources, 2, uintptr(unsafe.Pointer(inst)), uintptr(timeoutInMs), 0)
-	if eno != 0 {
-		return 0, Error{eno}
+func (inst *FindInstance) WaitForSources(timeoutInMs uint32) (bool, error) {
+	b, err := inst.rlock()
+	if err != nil {
+		return false, err
 	}
-	return int(ret), nil
+	defer inst.runlock()
+	return b.FindWaitForSources(inst.handle, timeoutInMs)
+}
+
+//WaitForSourcesContext is WaitForSources that returns ctx.Err() as soon as ctx is done.
+func (inst *FindInstance) WaitForSourcesContext(ctx context.Context, timeout time.Duration) (bool, error) {
+	return waitContext(ctx, timeout, func(timeoutInMs uint32) (bool, bool, error) {
+		changed, err := inst.WaitForSources(timeoutInMs)
+		return changed, changed, err
+	})
 }
 
 //This function will recover the current set of sources (i.e. the ones that exist right this second).
-func (inst *FindInstance) GetCurrentSources() []*Source {
-	var numSources uint32
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibFindGetCurrentSources, 2, uintptr(unsafe.Pointer(inst)), uintptr(unsafe.Pointer(&numSources)), 0)
-	if eno != 0 {
-		panic(eno)
+func (inst *FindInstance) GetCurrentSources() ([]*Source, error) {
+	b, err := inst.rlock()
+	if err != nil {
+		return nil, err
+	}
+	defer inst.runlock()
+	return b.FindGetCurrentSources(inst.handle)
+}
+
+//Returns copies of the current set of sources, unlike GetCurrentSources they stay valid after the next call and after
+//the finder is destroyed.
+func (inst *FindInstance) CurrentSources() ([]SourceInfo, error) {
+	b, err := inst.rlock()
+	if err != nil {
+		return nil, err
+	}
+	defer inst.runlock()
+
+	sources, err := b.FindGetCurrentSources(inst.handle)
+	if err != nil {
+		return nil, err
 	}
 
-	sources := make([]*Source, numSources)
+	infos := make([]SourceInfo, len(sources))
 	for i, s := range sources {
-		sources[i] = (*Source)(unsafe.Pointer(ret))
-		ret += unsafe.Sizeof(*s)
+		infos[i] = s.Info()
 	}
-	return sources
+	return infos, nil
 }
@@ -0,0 +1,100 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import "unsafe"
+
+//Returns the line stride of the frame, working out the tightly packed stride when none was specified.
+func videoFrameLineStride(vf *VideoFrameV2) int {
+	if vf.LineStride != 0 {
+		return int(vf.LineStride)
+	}
+
+	switch vf.FourCC {
+	case FourCCTypeUYVY, FourCCTypeUYVA:
+		return int(vf.Xres) * 2
+	default:
+		return int(vf.Xres) * 4
+	}
+}
+
+//Returns the number of bytes addressed by the Data field of the frame.
+func videoFrameDataSize(vf *VideoFrameV2) int {
+	stride := videoFrameLineStride(vf)
+	size := stride * int(vf.Yres)
+	if vf.FourCC == FourCCTypeUYVA {
+		size += stride / 2 * int(vf.Yres)
+	}
+	return size
+}
+
+//Returns the number of bytes addressed by the Data field of the frame.
+func audioFrameDataSize(af *AudioFrameV2) int {
+	if af.NumChannels <= 0 || af.NumSamples <= 0 {
+		return 0
+	}
+
+	stride := int(af.ChannelStride)
+	if stride == 0 {
+		stride = int(af.NumSamples) * 4
+	}
+	return stride*int(af.NumChannels-1) + int(af.NumSamples)*4
+}
+
+func cloneBytes(p *byte, n int) *byte {
+	if p == nil || n <= 0 {
+		return nil
+	}
+
+	b := make([]byte, n)
+	copy(b, unsafe.Slice(p, n))
+	return &b[0]
+}
+
+func cStringLen(p *byte) int {
+	if p == nil {
+		return 0
+	}
+
+	n := 0
+	for *(*byte)(unsafe.Add(unsafe.Pointer(p), n)) != 0 {
+		n++
+	}
+	return n
+}
+
+func cloneCString(p *byte) *byte {
+	if p == nil {
+		return nil
+	}
+	return cloneBytes(p, cStringLen(p)+1)
+}
+
+//Returns a copy of the frame that owns a copy of its data and metadata.
+func cloneVideoFrameV2(vf *VideoFrameV2) *VideoFrameV2 {
+	c := *vf
+	c.Data = cloneBytes(vf.Data, videoFrameDataSize(vf))
+	c.Metadata = cloneCString(vf.Metadata)
+	return &c
+}
+
+//Returns a copy of the frame that owns a copy of its data and metadata.
+func cloneAudioFrameV2(af *AudioFrameV2) *AudioFrameV2 {
+	c := *af
+	c.Data = (*float32)(unsafe.Pointer(cloneBytes((*byte)(unsafe.Pointer(af.Data)), audioFrameDataSize(af))))
+	c.Metadata = cloneCString(af.Metadata)
+	return &c
+}
+
+//Returns a copy of the frame that owns a copy of its data.
+func cloneMetadataFrame(mf *MetadataFrame) *MetadataFrame {
+	c := *mf
+	if mf.Length > 0 {
+		c.Data = cloneBytes(mf.Data, int(mf.Length))
+	} else {
+		c.Data = cloneCString(mf.Data)
+	}
+	return &c
+}
@@ -0,0 +1,65 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import "sync"
+
+//instance is what finders, senders, receivers and routers have in common: the library they were created with and
+//their handle in it.
+type instance struct {
+	lib    *library
+	handle Handle
+
+	//Held for reading by every call on the handle and for writing while it is destroyed, so that the handle is never
+	//destroyed while a call is still using it.
+	mu     sync.RWMutex
+	closed bool
+}
+
+//Returns the backend with the instance and the library locked for reading, the caller must call runlock when done
+//with it.
+func (inst *instance) rlock() (Backend, error) {
+	inst.mu.RLock()
+	if inst.closed {
+		inst.mu.RUnlock()
+		return nil, ErrClosed
+	}
+
+	b, err := inst.lib.rlock()
+	if err != nil {
+		inst.mu.RUnlock()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (inst *instance) runlock() {
+	inst.lib.mu.RUnlock()
+	inst.mu.RUnlock()
+}
+
+//Waits for the calls using the instance to return, marks it as closed and returns the backend to destroy it with.
+//The instance stays locked for writing until unlockClose is called, calls made meanwhile return ErrClosed once it is
+//unlocked. When the instance was already closed both return values are nil and nothing stays locked.
+func (inst *instance) lockClose() (Backend, error) {
+	inst.mu.Lock()
+	if inst.closed {
+		inst.mu.Unlock()
+		return nil, nil
+	}
+	inst.closed = true
+
+	b, err := inst.lib.rlock()
+	if err != nil {
+		inst.mu.Unlock()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (inst *instance) unlockClose() {
+	inst.lib.mu.RUnlock()
+	inst.mu.Unlock()
+}
@@ -0,0 +1,14 @@
+//go:build !ndidebug
+
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import "io"
+
+//Leak warnings are only compiled in with the ndidebug build tag.
+func trackLeak(o io.Closer) {}
+
+func untrackLeak(o io.Closer) {}
@@ -0,0 +1,34 @@
+//go:build ndidebug
+
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"io"
+	"log"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+//The addresses of the objects that have a leak finalizer set. Addresses are stored rather than the objects so that
+//they can still be collected.
+var trackedLeaks sync.Map
+
+//Logs a warning when o is garbage collected without having been closed.
+func trackLeak(o io.Closer) {
+	trackedLeaks.Store(reflect.ValueOf(o).Pointer(), struct{}{})
+	runtime.SetFinalizer(o, func(o io.Closer) {
+		trackedLeaks.Delete(reflect.ValueOf(o).Pointer())
+		log.Printf("ndi: %T %p was garbage collected without being closed", o, o)
+	})
+}
+
+func untrackLeak(o io.Closer) {
+	if _, ok := trackedLeaks.LoadAndDelete(reflect.ValueOf(o).Pointer()); ok {
+		runtime.SetFinalizer(o, nil)
+	}
+}
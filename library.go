@@ -0,0 +1,105 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//The environment variable that names the runtime library file to load, it overrides every other location.
+const RuntimePathEnv = "NDI_RUNTIME_PATH"
+
+//The environment variables the NDI runtime installers set to the directory containing the library, newest first.
+var runtimeDirEnvs = []string{"NDI_RUNTIME_DIR_V5", "NDI_RUNTIME_DIR_V4", "NDI_RUNTIME_DIR_V3"}
+
+//LoadAttempt records why loading the runtime from one path failed.
+type LoadAttempt struct {
+	Path string
+	Err  error
+}
+
+//LoadError is returned by LoadDefault when the runtime could not be loaded from any of the paths it tried.
+type LoadError struct {
+	Attempts []LoadAttempt
+}
+
+func (e *LoadError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("unable to load the ndi runtime, tried:")
+	for _, a := range e.Attempts {
+		sb.WriteString("\n\t")
+		sb.WriteString(a.Path)
+		sb.WriteString(": ")
+		sb.WriteString(a.Err.Error())
+	}
+	return sb.String()
+}
+
+func (e *LoadError) Unwrap() []error {
+	errs := make([]error, len(e.Attempts))
+	for i, a := range e.Attempts {
+		errs[i] = a.Err
+	}
+	return errs
+}
+
+//Returns the paths LoadDefault tries in order: the override, the runtime directories from the environment, the
+//standard install locations and finally the bare library names so the system loader can search its own paths.
+func runtimeCandidates() []string {
+	var paths []string
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+
+	if p := os.Getenv(RuntimePathEnv); p != "" {
+		add(p)
+	}
+
+	var dirs []string
+	for _, env := range runtimeDirEnvs {
+		if dir := os.Getenv(env); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	dirs = append(dirs, ndiLibDirs()...)
+
+	for _, dir := range dirs {
+		for _, name := range ndiLibNames {
+			add(filepath.Join(dir, name))
+		}
+	}
+
+	for _, name := range ndiLibNames {
+		add(name)
+	}
+	return paths
+}
+
+//Finds the NDI runtime library and initializes it. The path in NDI_RUNTIME_PATH is tried first, followed by the
+//NDI_RUNTIME_DIR_V5, V4 and V3 directories, the standard install locations and the system library search path.
+//When no candidate can be loaded the returned *LoadError lists every path that was tried. When the runtime is already
+//loaded this only adds a reference, see Acquire.
+func LoadDefault() error {
+	return Acquire()
+}
+
+func loadDefaultBackend() (Backend, error) {
+	loadErr := &LoadError{}
+	for _, p := range runtimeCandidates() {
+		b, err := loadLibraryBackend(p)
+		if err != nil {
+			loadErr.Attempts = append(loadErr.Attempts, LoadAttempt{p, err})
+			continue
+		}
+		return b, nil
+	}
+	return nil, loadErr
+}
@@ -0,0 +1,18 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+//The file names of the NDI runtime library on this platform.
+var ndiLibNames = []string{"libndi.dylib"}
+
+//The directories the NDI SDK and runtime installers put the library in.
+func ndiLibDirs() []string {
+	return []string{
+		"/usr/local/lib",
+		"/Library/NDI SDK for Apple/lib/macOS",
+		"/Library/NDI SDK for Apple/lib/x64",
+		"/Library/NDI 4 SDK for Apple/lib/x64",
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows && !darwin
+
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+//The file names of the NDI runtime library on this platform, the versioned names are what the SDK installs.
+var ndiLibNames = []string{"libndi.so.5", "libndi.so.4", "libndi.so.3", "libndi.so"}
+
+//The directories the NDI SDK and distribution packages put the library in.
+func ndiLibDirs() []string {
+	return []string{
+		"/usr/local/lib",
+		"/usr/lib",
+		"/usr/lib/x86_64-linux-gnu",
+		"/usr/lib/aarch64-linux-gnu",
+	}
+}
@@ -0,0 +1,36 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+//The file names of the NDI runtime library on this platform.
+var ndiLibNames = []string{ndiLibName()}
+
+func ndiLibName() string {
+	if runtime.GOARCH == "386" {
+		return "Processing.NDI.Lib.x86.dll"
+	}
+	return "Processing.NDI.Lib.x64.dll"
+}
+
+//The directories the NDI runtime installers put the library in.
+func ndiLibDirs() []string {
+	programFiles := os.Getenv("ProgramFiles")
+	if programFiles == "" {
+		programFiles = `C:\Program Files`
+	}
+
+	return []string{
+		filepath.Join(programFiles, "NDI", "NDI 5 Runtime", "v5"),
+		filepath.Join(programFiles, "NDI", "NDI 4 Runtime", "v4"),
+		filepath.Join(programFiles, "NewTek", "NDI 4 Runtime", "v4"),
+		filepath.Join(programFiles, "NewTek", "NewTek NDI 3 Runtime", "v3"),
+	}
+}
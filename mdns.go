@@ -0,0 +1,404 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//The multicast DNS group and port, RFC 6762.
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+//The service NDI sources are advertised as, every source is an instance of it named after the source.
+var ndiServiceName = []string{"_ndi", "_tcp", "local"}
+
+const (
+	dnsTypeA    = 1
+	dnsTypePTR  = 12
+	dnsTypeTXT  = 16
+	dnsTypeAAAA = 28
+	dnsTypeSRV  = 33
+
+	dnsClassIN = 1
+
+	//The top bit of the class is the cache-flush bit in records and the unicast-response bit in questions.
+	dnsClassMask = 0x7fff
+
+	dnsFlagResponse = 0x8000
+)
+
+var errDNSMessage = errors.New("malformed dns message")
+
+type dnsQuestion struct {
+	name       []string
+	typ, class uint16
+}
+
+type dnsRecord struct {
+	name       []string
+	typ, class uint16
+	ttl        uint32
+
+	//The decoded record data, which fields are used depends on the type.
+	target []string //PTR, SRV
+	port   uint16   //SRV
+	ip     net.IP   //A, AAAA
+}
+
+type dnsMessage struct {
+	id, flags uint16
+	questions []dnsQuestion
+	records   []dnsRecord
+}
+
+//Returns a key that compares names the way DNS does, without regard to ASCII case.
+func dnsNameKey(labels []string) string {
+	return strings.ToLower(strings.Join(labels, "\x00"))
+}
+
+//dnsBuilder encodes a DNS message, compressing repeated names.
+type dnsBuilder struct {
+	buf   []byte
+	names map[string]int
+}
+
+func newDNSBuilder(id, flags uint16, questions, records int) *dnsBuilder {
+	b := &dnsBuilder{names: make(map[string]int)}
+	b.buf = binary.BigEndian.AppendUint16(b.buf, id)
+	b.buf = binary.BigEndian.AppendUint16(b.buf, flags)
+	b.buf = binary.BigEndian.AppendUint16(b.buf, uint16(questions))
+	b.buf = binary.BigEndian.AppendUint16(b.buf, uint16(records))
+	b.buf = binary.BigEndian.AppendUint16(b.buf, 0)
+	b.buf = binary.BigEndian.AppendUint16(b.buf, 0)
+	return b
+}
+
+func (b *dnsBuilder) name(labels []string) {
+	for i, l := range labels {
+		key := dnsNameKey(labels[i:])
+		if off, ok := b.names[key]; ok {
+			b.buf = binary.BigEndian.AppendUint16(b.buf, 0xc000|uint16(off))
+			return
+		}
+
+		if len(b.buf) <= 0x3fff {
+			b.names[key] = len(b.buf)
+		}
+		b.buf = append(b.buf, byte(min(len(l), 63)))
+		b.buf = append(b.buf, l[:min(len(l), 63)]...)
+	}
+	b.buf = append(b.buf, 0)
+}
+
+func (b *dnsBuilder) question(q dnsQuestion) {
+	b.name(q.name)
+	b.buf = binary.BigEndian.AppendUint16(b.buf, q.typ)
+	b.buf = binary.BigEndian.AppendUint16(b.buf, q.class)
+}
+
+func (b *dnsBuilder) record(r dnsRecord) {
+	b.name(r.name)
+	b.buf = binary.BigEndian.AppendUint16(b.buf, r.typ)
+	b.buf = binary.BigEndian.AppendUint16(b.buf, r.class)
+	b.buf = binary.BigEndian.AppendUint32(b.buf, r.ttl)
+
+	lenOff := len(b.buf)
+	b.buf = append(b.buf, 0, 0)
+	switch r.typ {
+	case dnsTypePTR:
+		b.name(r.target)
+	case dnsTypeSRV:
+		b.buf = append(b.buf, 0, 0, 0, 0)
+		b.buf = binary.BigEndian.AppendUint16(b.buf, r.port)
+		b.name(r.target)
+	case dnsTypeA:
+		b.buf = append(b.buf, r.ip.To4()...)
+	case dnsTypeAAAA:
+		b.buf = append(b.buf, r.ip.To16()...)
+	case dnsTypeTXT:
+		b.buf = append(b.buf, 0)
+	}
+	binary.BigEndian.PutUint16(b.buf[lenOff:], uint16(len(b.buf)-lenOff-2))
+}
+
+//Reads the possibly compressed name at off and returns it with the offset of what follows it.
+func readDNSName(msg []byte, off int) ([]string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return nil, 0, errDNSMessage
+		}
+
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return labels, next, nil
+		case l&0xc0 == 0xc0:
+			if off+1 >= len(msg) || jumps > 16 {
+				return nil, 0, errDNSMessage
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			jumps++
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		case l&0xc0 != 0:
+			return nil, 0, errDNSMessage
+		default:
+			if off+1+l > len(msg) {
+				return nil, 0, errDNSMessage
+			}
+			labels = append(labels, string(msg[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+}
+
+func parseDNSMessage(msg []byte) (*dnsMessage, error) {
+	if len(msg) < 12 {
+		return nil, errDNSMessage
+	}
+
+	m := &dnsMessage{
+		id:    binary.BigEndian.Uint16(msg[0:]),
+		flags: binary.BigEndian.Uint16(msg[2:]),
+	}
+	numQuestions := int(binary.BigEndian.Uint16(msg[4:]))
+	numRecords := 0
+	for i := 6; i < 12; i += 2 {
+		numRecords += int(binary.BigEndian.Uint16(msg[i:]))
+	}
+
+	off := 12
+	for i := 0; i < numQuestions; i++ {
+		name, next, err := readDNSName(msg, off)
+		if err != nil || next+4 > len(msg) {
+			return nil, errDNSMessage
+		}
+
+		m.questions = append(m.questions, dnsQuestion{
+			name:  name,
+			typ:   binary.BigEndian.Uint16(msg[next:]),
+			class: binary.BigEndian.Uint16(msg[next+2:]),
+		})
+		off = next + 4
+	}
+
+	for i := 0; i < numRecords; i++ {
+		name, next, err := readDNSName(msg, off)
+		if err != nil || next+10 > len(msg) {
+			return nil, errDNSMessage
+		}
+
+		r := dnsRecord{
+			name:  name,
+			typ:   binary.BigEndian.Uint16(msg[next:]),
+			class: binary.BigEndian.Uint16(msg[next+2:]),
+			ttl:   binary.BigEndian.Uint32(msg[next+4:]),
+		}
+		start := next + 10
+		end := start + int(binary.BigEndian.Uint16(msg[next+8:]))
+		if end > len(msg) {
+			return nil, errDNSMessage
+		}
+
+		switch r.typ {
+		case dnsTypePTR:
+			if r.target, _, err = readDNSName(msg, start); err != nil {
+				return nil, err
+			}
+		case dnsTypeSRV:
+			if end-start < 7 {
+				return nil, errDNSMessage
+			}
+			r.port = binary.BigEndian.Uint16(msg[start+4:])
+			if r.target, _, err = readDNSName(msg, start+6); err != nil {
+				return nil, err
+			}
+		case dnsTypeA, dnsTypeAAAA:
+			if n := end - start; n != net.IPv4len && n != net.IPv6len {
+				return nil, errDNSMessage
+			}
+			r.ip = net.IP(append([]byte(nil), msg[start:end]...))
+		}
+
+		m.records = append(m.records, r)
+		off = end
+	}
+	return m, nil
+}
+
+//Collects the records that describe NDI sources from the responses to a browse.
+type mdnsResults struct {
+	instances map[string][]string
+	services  map[string]dnsRecord
+	addresses map[string][]net.IP
+}
+
+func newMDNSResults() *mdnsResults {
+	return &mdnsResults{
+		instances: make(map[string][]string),
+		services:  make(map[string]dnsRecord),
+		addresses: make(map[string][]net.IP),
+	}
+}
+
+func (r *mdnsResults) add(records []dnsRecord) {
+	serviceKey := dnsNameKey(ndiServiceName)
+	for _, rr := range records {
+		if rr.class&dnsClassMask != dnsClassIN {
+			continue
+		}
+
+		key := dnsNameKey(rr.name)
+		switch rr.typ {
+		case dnsTypePTR:
+			if key != serviceKey || len(rr.target) != len(ndiServiceName)+1 {
+				continue
+			}
+
+			//A record with a zero TTL says goodbye.
+			if rr.ttl == 0 {
+				delete(r.instances, dnsNameKey(rr.target))
+			} else {
+				r.instances[dnsNameKey(rr.target)] = rr.target
+			}
+		case dnsTypeSRV:
+			r.services[key] = rr
+		case dnsTypeA, dnsTypeAAAA:
+			r.addresses[key] = append(r.addresses[key], rr.ip)
+		}
+	}
+}
+
+//Returns the host a source runs on, preferring an IPv4 over an IPv6 address over the host name.
+func (r *mdnsResults) host(target []string) string {
+	var host net.IP
+	for _, ip := range r.addresses[dnsNameKey(target)] {
+		if host == nil || host.To4() == nil && ip.To4() != nil {
+			host = ip
+		}
+	}
+
+	if host == nil {
+		return strings.Join(target, ".")
+	}
+	return host.String()
+}
+
+//Returns the sources that were found sorted by name, with an empty address for the ones whose service record was not
+//seen.
+func (r *mdnsResults) sources() []SourceInfo {
+	var sources []SourceInfo
+	for key, instance := range r.instances {
+		si := SourceInfo{Name: instance[0]}
+		if srv, ok := r.services[key]; ok {
+			si.Address = net.JoinHostPort(r.host(srv.target), strconv.Itoa(int(srv.port)))
+		}
+		sources = append(sources, si)
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Name < sources[j].Name
+	})
+	return sources
+}
+
+//MDNSDiscoverer finds NDI sources by browsing for the _ndi._tcp service with multicast DNS. It does not need the NDI
+//runtime to be loaded. Queries are sent from an ephemeral port so that responders answer with unicast, RFC 6762
+//section 6.7.
+type MDNSDiscoverer struct {
+	//Where queries are sent, the multicast DNS group when nil.
+	Addr *net.UDPAddr
+
+	//How often the query is repeated while browsing, a second when zero.
+	QueryInterval time.Duration
+}
+
+//Browse queries for NDI sources until wait has elapsed and returns the ones that answered, sorted by name. It returns
+//ctx.Err() when ctx is done first.
+func (d *MDNSDiscoverer) Browse(ctx context.Context, wait time.Duration) ([]SourceInfo, error) {
+	addr := d.Addr
+	if addr == nil {
+		addr = mdnsGroup
+	}
+
+	interval := d.QueryInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	network := "udp4"
+	if addr.IP.To4() == nil {
+		network = "udp6"
+	}
+
+	conn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	q := newDNSBuilder(0, 0, 1, 0)
+	q.question(dnsQuestion{ndiServiceName, dnsTypePTR, dnsClassIN})
+
+	results := newMDNSResults()
+	buf := make([]byte, 9000)
+	deadline := time.Now().Add(wait)
+	var nextQuery time.Time
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		if !now.Before(deadline) {
+			return results.sources(), nil
+		}
+
+		if !now.Before(nextQuery) {
+			if _, err := conn.WriteToUDP(q.buf, addr); err != nil {
+				return nil, err
+			}
+			nextQuery = now.Add(interval)
+		}
+
+		readDeadline := now.Add(contextWaitSlice)
+		if nextQuery.Before(readDeadline) {
+			readDeadline = nextQuery
+		}
+		if deadline.Before(readDeadline) {
+			readDeadline = deadline
+		}
+		if err := conn.SetReadDeadline(readDeadline); err != nil {
+			return nil, err
+		}
+
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return nil, err
+		}
+
+		//Anything that is not a well formed response is ignored, other hosts share the port.
+		if msg, err := parseDNSMessage(buf[:n]); err == nil && msg.flags&dnsFlagResponse != 0 {
+			results.add(msg.records)
+		}
+	}
+}
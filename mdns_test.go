@@ -0,0 +1,122 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//Answers _ndi._tcp queries on loopback the way an NDI sender does, with the service, text and address records of
+//each source as additional records.
+func startMDNSResponder(t *testing.T, records []dnsRecord) *net.UDPAddr {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	checkErr(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 9000)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			q, err := parseDNSMessage(buf[:n])
+			if err != nil || len(q.questions) != 1 || dnsNameKey(q.questions[0].name) != dnsNameKey(ndiServiceName) {
+				continue
+			}
+
+			b := newDNSBuilder(q.id, dnsFlagResponse|0x0400, 0, len(records))
+			for _, r := range records {
+				b.record(r)
+			}
+			conn.WriteToUDP(b.buf, from)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func mdnsSourceRecords(name, host string, port uint16, ips ...net.IP) []dnsRecord {
+	instance := append([]string{name}, ndiServiceName...)
+	target := []string{host, "local"}
+	records := []dnsRecord{
+		{name: ndiServiceName, typ: dnsTypePTR, class: dnsClassIN, ttl: 4500, target: instance},
+		{name: instance, typ: dnsTypeSRV, class: dnsClassIN | 0x8000, ttl: 120, target: target, port: port},
+		{name: instance, typ: dnsTypeTXT, class: dnsClassIN | 0x8000, ttl: 4500},
+	}
+	for _, ip := range ips {
+		typ := uint16(dnsTypeA)
+		if ip.To4() == nil {
+			typ = dnsTypeAAAA
+		}
+		records = append(records, dnsRecord{name: target, typ: typ, class: dnsClassIN | 0x8000, ttl: 120, ip: ip})
+	}
+	return records
+}
+
+func TestMDNSDiscoverer(t *testing.T) {
+	var records []dnsRecord
+	records = append(records, mdnsSourceRecords("CAMERA (1)", "camera", 5961, net.ParseIP("fe80::1"), net.IPv4(10, 0, 0, 1))...)
+	records = append(records, mdnsSourceRecords("CAMERA (2.1)", "studio", 5962)...)
+	records = append(records, mdnsSourceRecords("GONE (1)", "gone", 5961, net.IPv4(10, 0, 0, 2))...)
+	records[len(records)-4].ttl = 0
+
+	//Records of other services and malformed messages are ignored.
+	records = append(records, dnsRecord{name: []string{"_http", "_tcp", "local"}, typ: dnsTypePTR, class: dnsClassIN, ttl: 4500, target: []string{"web", "_http", "_tcp", "local"}})
+
+	d := &MDNSDiscoverer{Addr: startMDNSResponder(t, records), QueryInterval: 50 * time.Millisecond}
+	sources, err := d.Browse(context.Background(), 200*time.Millisecond)
+	checkErr(t, err)
+
+	expected := []SourceInfo{
+		{"CAMERA (1)", "10.0.0.1:5961"},
+		{"CAMERA (2.1)", "studio.local:5962"},
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Fatalf("Expected %v, got %v.", expected, sources)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.Browse(ctx, time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the canceled context to stop browsing, got %v.", err)
+	}
+}
+
+func TestParseDNSMessage(t *testing.T) {
+	b := newDNSBuilder(1, dnsFlagResponse, 1, 1)
+	b.question(dnsQuestion{ndiServiceName, dnsTypePTR, dnsClassIN})
+	b.record(dnsRecord{name: ndiServiceName, typ: dnsTypePTR, class: dnsClassIN, ttl: 10, target: append([]string{"A (B)"}, ndiServiceName...)})
+
+	//The service name is written once and then pointed to: the header, the question, the record name pointer and
+	//fixed fields, and the instance label followed by a pointer.
+	if len(b.buf) != 12+17+4+2+10+6+2 {
+		t.Errorf("Expected the names to be compressed, the message is %d bytes.", len(b.buf))
+	}
+
+	m, err := parseDNSMessage(b.buf)
+	checkErr(t, err)
+	if m.id != 1 || len(m.questions) != 1 || len(m.records) != 1 || m.records[0].target[0] != "A (B)" {
+		t.Fatalf("Unexpected message %+v.", m)
+	}
+
+	for n := range b.buf {
+		if _, err := parseDNSMessage(b.buf[:n]); err == nil {
+			t.Errorf("Expected an error for the message truncated to %d bytes.", n)
+		}
+	}
+
+	//A pointer to itself must not loop forever.
+	loop := append(b.buf[:12:12], 0xc0, 12, 0, 1, 0, 1)
+	loop[5] = 1
+	if _, err := parseDNSMessage(loop); err == nil {
+		t.Error("Expected an error for a name that points to itself.")
+	}
+}
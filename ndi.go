@@ -6,46 +6,112 @@ package ndi
 
 import (
 	"errors"
-	"log"
-	"syscall"
-	"unsafe"
+	"io"
+	"sync"
 )
 
 var (
 	alreadyLoadedErr     = errors.New("library is already loaded")
 	loadProcsErr         = errors.New("failed to load library procs")
 	initializeLibraryErr = errors.New("unable to initialize library")
+	alreadyPooledErr     = errors.New("object is already in the object pool")
+	notPooledErr         = errors.New("object was not found in the object pool")
 )
 
+//The errors returned by this package, test for them with errors.Is.
 var (
-	ndiSharedLibrary syscall.Handle
-	funcPtrs         *ndiLIBv3
+	ErrNotLoaded    = errors.New("library is not loaded")
+	ErrCreateFailed = errors.New("unable to create instance")
+	ErrNotConnected = errors.New("not connected to a source")
+	ErrTimeout      = errors.New("operation timed out")
+	ErrNotSupported = errors.New("operation is not supported")
+	ErrClosed       = errors.New("instance is closed")
+
+	//Returned when a source reference does not match any of the sources a finder sees.
+	ErrSourceNotFound = errors.New("source not found")
+
+	//Wrapped by the errors the settings builders return for the names, groups and addresses they reject.
+	ErrInvalidSettings = errors.New("invalid settings")
+
+	//Wrapped by the errors returned for arguments outside of the range the runtime documents for them.
+	ErrOutOfRange = errors.New("value out of range")
 )
 
 type Tally struct {
 	OnProgram, OnPreview bool
 }
 
+//ObjectPool owns settings and instances until CloseAll closes them, in the reverse order of their creation so that
+//instances are destroyed before the settings they were created from. It is safe for concurrent use. Closing a pooled
+//object directly is allowed, CloseAll will then close it again which does nothing.
 type ObjectPool struct {
-	objects map[interface{}]struct{}
+	mu      sync.Mutex
+	objects []io.Closer
+	pooled  map[io.Closer]struct{}
 }
 
 func NewObjectPool() *ObjectPool {
-	return &ObjectPool{make(map[interface{}]struct{})}
+	return &ObjectPool{pooled: make(map[io.Closer]struct{})}
 }
 
-func (p *ObjectPool) Register(o interface{}) {
-	if _, ok := p.objects[o]; ok {
-		log.Fatalln("object is already in the object pool")
+//Hands ownership of o to the pool.
+func (p *ObjectPool) Register(o io.Closer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.pooled[o]; ok {
+		return alreadyPooledErr
 	}
-	p.objects[o] = struct{}{}
+
+	p.pooled[o] = struct{}{}
+	p.objects = append(p.objects, o)
+	return nil
 }
 
-func (p *ObjectPool) Release(o interface{}) {
-	if _, ok := p.objects[o]; !ok {
-		log.Fatalln("object was not found in the object pool")
+//Takes o out of the pool without closing it, the caller owns it from then on.
+func (p *ObjectPool) Release(o io.Closer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.pooled[o]; !ok {
+		return notPooledErr
+	}
+
+	delete(p.pooled, o)
+	for i, po := range p.objects {
+		if po == o {
+			p.objects = append(p.objects[:i], p.objects[i+1:]...)
+			break
+		}
 	}
-	delete(p.objects, o)
+	return nil
+}
+
+//Closes every object in the pool, newest first, and empties it. All objects are closed even when some fail, the
+//errors are joined.
+func (p *ObjectPool) CloseAll() error {
+	p.mu.Lock()
+	objects := p.objects
+	p.objects = nil
+	p.pooled = make(map[io.Closer]struct{})
+	p.mu.Unlock()
+
+	var errs []error
+	for i := len(objects) - 1; i >= 0; i-- {
+		if err := objects[i].Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+//Adds an object created by the pool, it is new so it can not be pooled already.
+func (p *ObjectPool) add(o io.Closer) {
+	p.mu.Lock()
+	p.pooled[o] = struct{}{}
+	p.objects = append(p.objects, o)
+	p.mu.Unlock()
+	trackLeak(o)
 }
 
 type SendCreateSettings struct {
@@ -54,131 +120,214 @@ type SendCreateSettings struct {
 }
 
 func (p *ObjectPool) NewSendCreateSettings(name, groups string, clockVideo, clockAudio bool) *SendCreateSettings {
-	var bNamePtr *byte
-	if name != "" {
-		bName := make([]byte, len(name)+1)
-		copy(bName, name)
-		bNamePtr = &bName[0]
-	}
-
-	var bGroupsPtr *byte
-	if groups != "" {
-		bGroups := make([]byte, len(groups)+1)
-		copy(bGroups, groups)
-		bGroupsPtr = &bGroups[0]
-	}
-
-	o := &SendCreateSettings{bNamePtr, bGroupsPtr, clockVideo, clockAudio}
-	p.Register(o)
+	o := &SendCreateSettings{cStringPtr(name), cStringPtr(groups), clockVideo, clockAudio}
+	p.add(o)
 	return o
 }
 
+//Close drops the strings of the settings, they must not be used to create instances afterwards.
+func (o *SendCreateSettings) Close() error {
+	untrackLeak(o)
+	o.ndiName, o.groups = nil, nil
+	return nil
+}
+
 type FindCreateSettings struct {
 	showLocalSources bool
 	groups, extraIPs *byte
 }
 
 func (p *ObjectPool) NewFindCreateSettings(showLocalSources bool, groups, ips string) *FindCreateSettings {
-	var bGroupsPtr *byte
-	if groups != "" {
-		bGroups := make([]byte, len(groups)+1)
-		copy(bGroups, groups)
-		bGroupsPtr = &bGroups[0]
-	}
-
-	var bIPsPtr *byte
-	if ips != "" {
-		bIPs := make([]byte, len(ips)+1)
-		copy(bIPs, ips)
-		bIPsPtr = &bIPs[0]
-	}
-
 	o := &FindCreateSettings{
 		showLocalSources: showLocalSources,
-		groups:           bGroupsPtr,
-		extraIPs:         bIPsPtr,
+		groups:           cStringPtr(groups),
+		extraIPs:         cStringPtr(ips),
 	}
 
-	p.Register(o)
+	p.add(o)
 	return o
 }
 
-func LoadAndInitialize(path string) error {
-	if ndiSharedLibrary != 0 {
-		return alreadyLoadedErr
-	}
+//Close drops the strings of the settings, they must not be used to create instances afterwards.
+func (o *FindCreateSettings) Close() error {
+	untrackLeak(o)
+	o.groups, o.extraIPs = nil, nil
+	return nil
+}
 
-	var err error
-	if ndiSharedLibrary, err = syscall.LoadLibrary(path); err != nil {
-		return err
+type RoutingCreateSettings struct {
+	ndiName, groups *byte
+}
+
+func (p *ObjectPool) NewRoutingCreateSettings(name, groups string) *RoutingCreateSettings {
+	o := &RoutingCreateSettings{cStringPtr(name), cStringPtr(groups)}
+	p.add(o)
+	return o
+}
+
+//Close drops the strings of the settings, they must not be used to create instances afterwards.
+func (o *RoutingCreateSettings) Close() error {
+	untrackLeak(o)
+	o.ndiName, o.groups = nil, nil
+	return nil
+}
+
+//library is a loaded and initialized backend. Instances keep a reference to the library they were created with and
+//hold its lock for reading while they call into it, so the backend is only destroyed once no call is in flight and
+//every call made after that fails with ErrNotLoaded.
+type library struct {
+	mu      sync.RWMutex
+	backend Backend
+}
+
+var (
+	libMu   sync.Mutex
+	lib     *library
+	libRefs int
+)
+
+//Returns the backend with the library locked for reading, the caller must unlock l.mu when done with it.
+func (l *library) rlock() (Backend, error) {
+	if l == nil {
+		return nil, ErrNotLoaded
 	}
 
-	var ndiLoadProc uintptr
-	if ndiLoadProc, err = syscall.GetProcAddress(ndiSharedLibrary, "NDIlib_v3_load"); err != nil {
-		syscall.FreeLibrary(ndiSharedLibrary)
-		ndiSharedLibrary = 0
-		return err
+	l.mu.RLock()
+	if l.backend == nil {
+		l.mu.RUnlock()
+		return nil, ErrNotLoaded
 	}
+	return l.backend, nil
+}
 
-	var (
-		ret uintptr
-		eno syscall.Errno
-	)
+//Returns the library that is currently loaded locked for reading, the caller must unlock l.mu when done with it.
+func loadedLibrary() (*library, Backend, error) {
+	libMu.Lock()
+	l := lib
+	libMu.Unlock()
 
-	if ret, _, eno = syscall.Syscall(ndiLoadProc, 0, 0, 0, 0); eno != 0 {
-		syscall.FreeLibrary(ndiSharedLibrary)
-		ndiSharedLibrary = 0
-		return eno
+	b, err := l.rlock()
+	if err != nil {
+		return nil, nil, err
 	}
+	return l, b, nil
+}
+
+//Adds a reference to the loaded library. When nothing is loaded yet load is called with a nil backend and the backend
+//it returns is initialized, otherwise it is called with the current backend and may refuse to share it.
+func acquire(load func(current Backend) (Backend, error)) error {
+	libMu.Lock()
+	defer libMu.Unlock()
 
-	funcPtrs = (*ndiLIBv3)(unsafe.Pointer(ret))
-	if funcPtrs == nil {
-		syscall.FreeLibrary(ndiSharedLibrary)
-		ndiSharedLibrary = 0
-		return loadProcsErr
+	var current Backend
+	if lib != nil {
+		current = lib.backend
 	}
 
-	if ret, _, eno = syscall.Syscall(funcPtrs.NDIlibInitialize, 0, 0, 0, 0); eno != 0 {
-		syscall.FreeLibrary(ndiSharedLibrary)
-		ndiSharedLibrary = 0
-		return eno
+	b, err := load(current)
+	if err != nil {
+		return err
 	}
 
-	if ret == 0 {
-		syscall.FreeLibrary(ndiSharedLibrary)
-		ndiSharedLibrary = 0
-		return initializeLibraryErr
+	if current == nil {
+		if err := b.Initialize(); err != nil {
+			return err
+		}
+		lib = &library{backend: b}
 	}
+
+	libRefs++
 	return nil
 }
 
-func DestroyAndUnload() {
-	if ndiSharedLibrary == 0 {
-		return
+//Acquire adds a reference to the NDI runtime, finding and initializing it with the same search as LoadDefault when no
+//other component holds a reference. It is safe to call from multiple goroutines, every successful call must be
+//balanced by a call to Release.
+func Acquire() error {
+	return acquire(func(current Backend) (Backend, error) {
+		if current != nil {
+			return current, nil
+		}
+		return loadDefaultBackend()
+	})
+}
+
+//Release drops a reference taken by Acquire or one of the Load functions. The runtime is destroyed and unloaded when
+//the last reference is released, after waiting for calls that are still in flight. Instances created before that
+//return ErrNotLoaded from then on.
+func Release() error {
+	libMu.Lock()
+	defer libMu.Unlock()
+
+	if lib == nil {
+		return ErrNotLoaded
 	}
 
-	if funcPtrs != nil {
-		if _, _, eno := syscall.Syscall(funcPtrs.NDIlibDestroy, 0, 0, 0, 0); eno != 0 {
-			panic(eno)
-		}
+	if libRefs--; libRefs > 0 {
+		return nil
 	}
 
-	syscall.FreeLibrary(ndiSharedLibrary)
-	ndiSharedLibrary = 0
+	l := lib
+	lib = nil
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.backend
+	l.backend = nil
+	return b.Destroy()
+}
+
+//Loads the NDI runtime library at path and initializes it, or adds a reference when the runtime is already loaded.
+func LoadAndInitialize(path string) error {
+	return acquire(func(current Backend) (Backend, error) {
+		if current != nil {
+			return current, nil
+		}
+		return loadLibraryBackend(path)
+	})
+}
+
+//Initializes the package with the given backend instead of the NDI runtime library. It adds a reference when the same
+//backend is already loaded and fails when a different one is.
+func LoadAndInitializeBackend(b Backend) error {
+	return acquire(func(current Backend) (Backend, error) {
+		if current != nil && current != b {
+			return nil, alreadyLoadedErr
+		}
+		return b, nil
+	})
+}
+
+//Releases the reference taken by one of the Load functions, see Release.
+func DestroyAndUnload() error {
+	return Release()
+}
+
+//Returns the API level that was negotiated with the loaded runtime.
+func LoadedAPILevel() (APILevel, error) {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return 0, err
+	}
+	defer l.mu.RUnlock()
+	return b.APILevel(), nil
 }
 
-func Version() string {
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibVersion, 0, 0, 0, 0)
-	if eno != 0 {
-		panic(eno)
+func Version() (string, error) {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return "", err
 	}
-	return goStringFromConst(ret)
+	defer l.mu.RUnlock()
+	return b.Version()
 }
 
-func IsSupportedCPU() bool {
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibIsSupportedCPU, 0, 0, 0, 0)
-	if eno != 0 {
-		panic(eno)
+func IsSupportedCPU() (bool, error) {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return false, err
 	}
-	return ret != 0
+	defer l.mu.RUnlock()
+	return b.IsSupportedCPU()
 }
@@ -5,28 +5,272 @@
 package ndi
 
 import (
-	"os"
-	"path"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
-const ndiLibName = "Processing.NDI.Lib.x64.dll"
-
 func doInit(t *testing.T) {
-	libDir := os.Getenv("NDI_RUNTIME_DIR_V3")
-	if libDir == "" {
-		t.Fatal("ndi sdk is not installed")
+	if err := LoadDefault(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotLoaded(t *testing.T) {
+	if _, err := Version(); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("Expected ErrNotLoaded, got %v.", err)
+	}
+
+	var inst SendInstance
+	if err := inst.SendVideoV2(NewVideoFrameV2()); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("Expected ErrNotLoaded, got %v.", err)
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	var err error = Error{1460}
+	if !errors.Is(err, ErrTimeout) {
+		t.Error("Error 1460 is not reported as a timeout.")
+	}
+
+	if errors.Is(Error{syscall.EINVAL}, ErrTimeout) || !errors.Is(Error{syscall.EINVAL}, syscall.EINVAL) {
+		t.Error("Error does not unwrap to its error number.")
+	}
+
+	var ndiErr Error
+	if !errors.As(fmt.Errorf("wrapped: %w", err), &ndiErr) || ndiErr.Errno != 1460 {
+		t.Error("Wrapped error could not be unwrapped with errors.As.")
+	}
+}
+
+func TestReferenceCounting(t *testing.T) {
+	fake := NewFakeBackend()
+	for i := 0; i < 2; i++ {
+		if err := LoadAndInitializeBackend(fake); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := LoadAndInitializeBackend(NewFakeBackend()); err == nil {
+		t.Error("A second backend was loaded while another one is in use.")
+	}
+
+	pool := NewObjectPool()
+	inst, err := NewFindInstanceV2(pool.NewFindCreateSettings(true, "", ""))
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if err := LoadAndInitialize(path.Join(libDir, ndiLibName)); err != nil {
+	if err := Release(); err != nil {
 		t.Fatal(err)
 	}
+	if _, err := inst.GetCurrentSources(); err != nil {
+		t.Errorf("Instance failed while a reference is still held: %v.", err)
+	}
+
+	if err := Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inst.GetCurrentSources(); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("Expected ErrNotLoaded after the last release, got %v.", err)
+	}
+	if _, err := NewFindInstanceV2(pool.NewFindCreateSettings(true, "", "")); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("Expected ErrNotLoaded after the last release, got %v.", err)
+	}
+	if err := Release(); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("Expected ErrNotLoaded from an unbalanced release, got %v.", err)
+	}
+}
+
+func TestConcurrentAcquire(t *testing.T) {
+	fake := NewFakeBackend()
+	if err := LoadAndInitializeBackend(fake); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := LoadAndInitializeBackend(fake); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := Version(); err != nil {
+					t.Error(err)
+				}
+				if err := Release(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Version(); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("Expected ErrNotLoaded after the last release, got %v.", err)
+	}
+}
+
+type closeRecorder struct {
+	id     int
+	closed *[]int
+}
+
+func (c *closeRecorder) Close() error {
+	*c.closed = append(*c.closed, c.id)
+	return nil
+}
+
+func TestObjectPool(t *testing.T) {
+	if err := LoadAndInitializeBackend(NewFakeBackend()); err != nil {
+		t.Fatal(err)
+	}
+	defer DestroyAndUnload()
+
+	var closed []int
+	pool := NewObjectPool()
+	first, second := &closeRecorder{1, &closed}, &closeRecorder{2, &closed}
+	if err := pool.Register(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Register(first); err == nil {
+		t.Error("Registering an object twice did not fail.")
+	}
+
+	settings := pool.NewFindCreateSettings(true, "", "")
+	inst, err := NewFindInstanceV2(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Register(inst); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Register(second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pool.Release(&closeRecorder{3, &closed}); err == nil {
+		t.Error("Releasing an object that is not pooled did not fail.")
+	}
+
+	if err := pool.CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(closed) != 2 || closed[0] != 2 || closed[1] != 1 {
+		t.Errorf("Expected the objects to be closed in reverse order, got %v.", closed)
+	}
+	if settings.groups != nil || settings.extraIPs != nil {
+		t.Error("Closing the settings did not drop their strings.")
+	}
+	if _, err := inst.GetCurrentSources(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v.", err)
+	}
+	if err := inst.Close(); err != nil {
+		t.Errorf("Closing an instance twice failed: %v.", err)
+	}
+}
+
+func TestDestroyWaitsForCalls(t *testing.T) {
+	fake := doFakeInit(t)
+	defer DestroyAndUnload()
+
+	fake.AddSource("CAMERA (1)", "10.0.0.1:5961")
+	settings := NewRecvCreateSettings()
+	settings.SourceToConnectTo = *SourceInfo{Name: "CAMERA (1)"}.Source()
+	recv := must(NewRecvInstanceV2(settings))
+
+	captured := make(chan error, 1)
+	go func() {
+		_, err := recv.CaptureV2(nil, nil, nil, 200)
+		captured <- err
+	}()
+
+	for deadline := time.Now().Add(5 * time.Second); fake.WaitingCaptures("CAMERA (1)") == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the capture to start.")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	//Destroying the instance while the capture waits must wait for it instead of pulling the handle from under it, the
+	//fake backend fails destroying a receiver that a capture is still using.
+	checkErr(t, recv.Destroy())
+
+	select {
+	case err := <-captured:
+		checkErr(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the capture to return.")
+	}
+
+	if _, err := recv.CaptureV2(nil, nil, nil, 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Destroy, got %v.", err)
+	}
+}
+
+func TestMissingProc(t *testing.T) {
+	b := &libraryBackend{level: APILevel3, funcPtrs: new(ndiLIBv5)}
+	if _, err := b.Version(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v.", err)
+	}
+}
+
+func TestRuntimeCandidates(t *testing.T) {
+	override := filepath.Join(t.TempDir(), "override")
+	t.Setenv(RuntimePathEnv, override)
+	t.Setenv("NDI_RUNTIME_DIR_V5", "")
+	t.Setenv("NDI_RUNTIME_DIR_V4", "")
+	t.Setenv("NDI_RUNTIME_DIR_V3", "v3dir")
+
+	paths := runtimeCandidates()
+	if paths[0] != override {
+		t.Fatalf("Expected the override to be tried first, got %s.", paths[0])
+	}
+	if want := filepath.Join("v3dir", ndiLibNames[0]); paths[1] != want {
+		t.Errorf("Expected %s to be tried second, got %s.", want, paths[1])
+	}
+	if last := paths[len(paths)-1]; last != ndiLibNames[len(ndiLibNames)-1] {
+		t.Errorf("Expected the bare library name to be tried last, got %s.", last)
+	}
+
+	//Without an installed runtime every candidate fails to load and the error lists all of them.
+	err := LoadDefault()
+	if err == nil {
+		DestroyAndUnload()
+		t.Skip("runtime is installed")
+	}
+
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || len(loadErr.Attempts) != len(paths) {
+		t.Fatalf("Expected a LoadError with %d attempts, got %v.", len(paths), err)
+	}
+	if loadErr.Attempts[0].Path != override {
+		t.Errorf("Expected the first attempt to be %s, got %s.", override, loadErr.Attempts[0].Path)
+	}
 }
 
 func TestInitialization(t *testing.T) {
 	doInit(t)
-	t.Logf("Version string is: %s", Version())
-	DestroyAndUnload()
+
+	version, err := Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Version string is: %s", version)
+
+	if err := DestroyAndUnload(); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestFrame(t *testing.T) {
@@ -35,7 +279,10 @@ func TestFrame(t *testing.T) {
 
 	pool := NewObjectPool()
 	settings := pool.NewSendCreateSettings("ndi-go test", "", true, false)
-	inst := NewSendInstance(settings)
+	inst, err := NewSendInstance(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	frameData := make([]byte, 1920*1080*4)
 	frame := NewVideoFrameV2()
@@ -45,6 +292,11 @@ func TestFrame(t *testing.T) {
 	frame.LineStride = 1920 * 4
 	frame.Data = &frameData[0]
 
-	inst.SendVideoV2(frame)
-	inst.Destroy()
+	if err := inst.SendVideoV2(frame); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := inst.Destroy(); err != nil {
+		t.Fatal(err)
+	}
 }
@@ -0,0 +1,213 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import "fmt"
+
+//The highest preset number a PTZ camera stores.
+const MaxPTZPreset = 99
+
+var ptzNotSupportedErr = fmt.Errorf("source is not a PTZ camera: %w", ErrNotSupported)
+
+//PTZ controls the PTZ camera a receive instance is connected to. Before every command it checks that the source is a
+//PTZ camera, asking the runtime again after the receive instance captured a FrameTypeStatusChange frame since that is
+//when the runtime learns about it, and returns an error wrapping ErrNotSupported when it is not. Arguments outside of
+//their documented range are rejected with an error wrapping ErrOutOfRange. Commands the runtime could not send, because
+//the connection was lost, return ErrNotConnected.
+type PTZ struct {
+	recv      *RecvInstance
+	supported recvCapability
+}
+
+//PTZ returns a controller for the camera the receive instance is connected to. The controller is only valid as long as
+//the receive instance is.
+func (inst *RecvInstance) PTZ() *PTZ {
+	return &PTZ{recv: inst}
+}
+
+//IsSupported returns whether the source is a PTZ camera. This is only known once connected, a source that is not one
+//yet is asked about again on every call.
+func (p *PTZ) IsSupported() (bool, error) {
+	return p.supported.check(p.recv, Backend.RecvPtzIsSupported)
+}
+
+//Checks that the source is a PTZ camera and sends the command with fn.
+func (p *PTZ) command(fn func(b Backend, inst Handle) (bool, error)) error {
+	return p.recv.command(&p.supported, Backend.RecvPtzIsSupported, ptzNotSupportedErr, fn)
+}
+
+func checkRange(what string, v, lo, hi float32) error {
+	if !(v >= lo && v <= hi) {
+		return fmt.Errorf("%w: %s %v is outside [%v, %v]", ErrOutOfRange, what, v, lo, hi)
+	}
+	return nil
+}
+
+func checkPreset(preset int) error {
+	if preset < 0 || preset > MaxPTZPreset {
+		return fmt.Errorf("%w: preset %d is outside [0, %d]", ErrOutOfRange, preset, MaxPTZPreset)
+	}
+	return nil
+}
+
+//Zoom sets the zoom level, from 0 (zoomed in) to 1 (zoomed out).
+func (p *PTZ) Zoom(zoom float32) error {
+	if err := checkRange("zoom", zoom, 0, 1); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzZoom(inst, zoom)
+	})
+}
+
+//ZoomSpeed zooms continuously at a speed from -1 (zoom outwards) to 1 (zoom inwards), 0 stops zooming.
+func (p *PTZ) ZoomSpeed(speed float32) error {
+	if err := checkRange("zoom speed", speed, -1, 1); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzZoomSpeed(inst, speed)
+	})
+}
+
+//PanTilt moves to a position, pan from -1 (left) to 1 (right) and tilt from -1 (bottom) to 1 (top).
+func (p *PTZ) PanTilt(pan, tilt float32) error {
+	if err := checkRange("pan", pan, -1, 1); err != nil {
+		return err
+	}
+	if err := checkRange("tilt", tilt, -1, 1); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzPanTilt(inst, pan, tilt)
+	})
+}
+
+//PanTiltSpeed moves continuously, pan at a speed from -1 (right) to 1 (left) and tilt at a speed from -1 (down) to 1
+//(up). Speeds of 0 stop moving.
+func (p *PTZ) PanTiltSpeed(panSpeed, tiltSpeed float32) error {
+	if err := checkRange("pan speed", panSpeed, -1, 1); err != nil {
+		return err
+	}
+	if err := checkRange("tilt speed", tiltSpeed, -1, 1); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzPanTiltSpeed(inst, panSpeed, tiltSpeed)
+	})
+}
+
+//StorePreset stores the current position, zoom and focus as a preset from 0 to MaxPTZPreset.
+func (p *PTZ) StorePreset(preset int) error {
+	if err := checkPreset(preset); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzStorePreset(inst, preset)
+	})
+}
+
+//RecallPreset moves to a stored preset at a speed from 0 (slowest) to 1 (fastest).
+func (p *PTZ) RecallPreset(preset int, speed float32) error {
+	if err := checkPreset(preset); err != nil {
+		return err
+	}
+	if err := checkRange("recall speed", speed, 0, 1); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzRecallPreset(inst, preset, speed)
+	})
+}
+
+//AutoFocus puts the camera in auto focus.
+func (p *PTZ) AutoFocus() error {
+	return p.command(Backend.RecvPtzAutoFocus)
+}
+
+//Focus sets the focus manually, from 0 (focused infinitely far away) to 1 (focused as close as possible).
+func (p *PTZ) Focus(focus float32) error {
+	if err := checkRange("focus", focus, 0, 1); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzFocus(inst, focus)
+	})
+}
+
+//FocusSpeed focuses manually and continuously at a speed from -1 (focus outwards) to 1 (focus inwards).
+func (p *PTZ) FocusSpeed(speed float32) error {
+	if err := checkRange("focus speed", speed, -1, 1); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzFocusSpeed(inst, speed)
+	})
+}
+
+//WhiteBalanceAuto puts the camera in auto white balance.
+func (p *PTZ) WhiteBalanceAuto() error {
+	return p.command(Backend.RecvPtzWhiteBalanceAuto)
+}
+
+//WhiteBalanceIndoor sets the white balance for indoor lighting.
+func (p *PTZ) WhiteBalanceIndoor() error {
+	return p.command(Backend.RecvPtzWhiteBalanceIndoor)
+}
+
+//WhiteBalanceOutdoor sets the white balance for outdoor lighting.
+func (p *PTZ) WhiteBalanceOutdoor() error {
+	return p.command(Backend.RecvPtzWhiteBalanceOutdoor)
+}
+
+//WhiteBalanceOneshot measures the white balance once from the current picture and keeps it.
+func (p *PTZ) WhiteBalanceOneshot() error {
+	return p.command(Backend.RecvPtzWhiteBalanceOneshot)
+}
+
+//WhiteBalanceManual sets the red and blue gains manually, each from 0 to 1.
+func (p *PTZ) WhiteBalanceManual(red, blue float32) error {
+	if err := checkRange("red", red, 0, 1); err != nil {
+		return err
+	}
+	if err := checkRange("blue", blue, 0, 1); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzWhiteBalanceManual(inst, red, blue)
+	})
+}
+
+//ExposureAuto puts the camera in auto exposure.
+func (p *PTZ) ExposureAuto() error {
+	return p.command(Backend.RecvPtzExposureAuto)
+}
+
+//ExposureManual sets the exposure level manually, from 0 (dark) to 1 (light).
+func (p *PTZ) ExposureManual(level float32) error {
+	if err := checkRange("exposure level", level, 0, 1); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzExposureManual(inst, level)
+	})
+}
+
+//ExposureManualV2 sets the iris, gain and shutter speed manually, each from 0 to 1. It needs a runtime with
+//APILevel5 and returns ErrNotSupported otherwise.
+func (p *PTZ) ExposureManualV2(iris, gain, shutterSpeed float32) error {
+	if err := checkRange("iris", iris, 0, 1); err != nil {
+		return err
+	}
+	if err := checkRange("gain", gain, 0, 1); err != nil {
+		return err
+	}
+	if err := checkRange("shutter speed", shutterSpeed, 0, 1); err != nil {
+		return err
+	}
+	return p.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvPtzExposureManualV2(inst, iris, gain, shutterSpeed)
+	})
+}
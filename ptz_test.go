@@ -0,0 +1,91 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+//Captures until a status change frame arrives.
+func captureStatusChange(t *testing.T, recv *RecvInstance) {
+	t.Helper()
+	for {
+		ft, err := recv.CaptureV2(nil, nil, nil, 5000)
+		checkErr(t, err)
+		switch ft {
+		case FrameTypeStatusChange:
+			return
+		case FrameTypeNone:
+			t.Fatal("Timed out waiting for the status change.")
+		}
+	}
+}
+
+func TestPTZ(t *testing.T) {
+	fake, _, recv := withFakeRecv(t, "CAMERA (1)")
+	ptz := recv.PTZ()
+
+	if err := ptz.Zoom(0.5); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported before the source is a PTZ camera, got %v.", err)
+	}
+
+	fake.SetPTZSupported("CAMERA (1)", true)
+	captureStatusChange(t, recv)
+	if ok := must(ptz.IsSupported()); !ok {
+		t.Fatal("Expected PTZ to be supported after the status change.")
+	}
+
+	checkErr(t, ptz.Zoom(0.25))
+	checkErr(t, ptz.PanTilt(-0.5, 0.5))
+	checkErr(t, ptz.PanTiltSpeed(1, -1))
+	checkErr(t, ptz.StorePreset(3))
+	checkErr(t, ptz.PanTilt(1, 1))
+	checkErr(t, ptz.RecallPreset(3, 0.75))
+	checkErr(t, ptz.Focus(0.1))
+	checkErr(t, ptz.WhiteBalanceManual(0.2, 0.8))
+	checkErr(t, ptz.ExposureManual(0.6))
+
+	state, _ := fake.PTZState("CAMERA (1)")
+	want := FakePTZ{
+		Zoom: 0.25, Pan: -0.5, Tilt: 0.5, PanSpeed: 1, TiltSpeed: -1,
+		Preset: 3, RecallSpeed: 0.75,
+		Focus:        0.1,
+		WhiteBalance: "manual", Red: 0.2, Blue: 0.8,
+		Exposure: 0.6,
+	}
+	if state != want {
+		t.Errorf("Unexpected camera state %+v, expected %+v.", state, want)
+	}
+
+	if err := ptz.RecallPreset(4, 1); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Expected a preset that was never stored to fail, got %v.", err)
+	}
+
+	if err := ptz.ExposureManualV2(0.5, 0.5, 0.5); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported below APILevel5, got %v.", err)
+	}
+
+	for _, err := range []error{
+		ptz.Zoom(1.5),
+		ptz.ZoomSpeed(-2),
+		ptz.PanTilt(0, float32(math.NaN())),
+		ptz.StorePreset(MaxPTZPreset + 1),
+		ptz.RecallPreset(0, -0.1),
+		ptz.WhiteBalanceManual(0, 2),
+	} {
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Expected ErrOutOfRange, got %v.", err)
+		}
+	}
+
+	//The source stops being a PTZ camera, which is noticed after the status change.
+	fake.SetPTZSupported("CAMERA (1)", false)
+	captureStatusChange(t, recv)
+	if err := ptz.AutoFocus(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported after the source stopped being a PTZ camera, got %v.", err)
+	}
+}
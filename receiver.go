@@ -0,0 +1,386 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+//How long a receiver waits for a frame in one go, this bounds how long it takes to notice that it was stopped.
+const receiverCaptureTimeout = time.Second
+
+//How long a receiver waits before capturing again after the connection was lost.
+const receiverRetryDelay = 100 * time.Millisecond
+
+//ReceiverFrames selects the types of frames a Receiver captures.
+type ReceiverFrames uint
+
+const (
+	ReceiveVideo ReceiverFrames = 1 << iota
+	ReceiveAudio
+	ReceiveMetadata
+
+	ReceiveAll = ReceiveVideo | ReceiveAudio | ReceiveMetadata
+)
+
+type ReceiverStatus int
+
+const (
+	//The settings of the source changed, for instance it is now known to be a PTZ camera.
+	ReceiverStatusChanged ReceiverStatus = iota
+
+	//The connection to the source was lost, the runtime keeps trying to connect again.
+	ReceiverConnectionLost
+
+	//The connection to the source was made again after it was lost.
+	ReceiverReconnected
+)
+
+func (s ReceiverStatus) String() string {
+	switch s {
+	case ReceiverStatusChanged:
+		return "status changed"
+	case ReceiverConnectionLost:
+		return "connection lost"
+	case ReceiverReconnected:
+		return "reconnected"
+	default:
+		return "unknown"
+	}
+}
+
+//BackpressurePolicy decides what a Receiver does with a frame when the consumer has not kept up and the channel for its
+//type is full.
+type BackpressurePolicy int
+
+const (
+	//Capturing waits until there is room, the runtime queues or drops frames meanwhile.
+	BackpressureBlock BackpressurePolicy = iota
+
+	//The oldest frame in the channel is dropped to make room, so that the consumer catches up.
+	BackpressureDropOldest
+
+	//The new frame is dropped, so that the consumer sees an unbroken run of frames.
+	BackpressureDropNewest
+)
+
+func (p BackpressurePolicy) String() string {
+	switch p {
+	case BackpressureBlock:
+		return "block"
+	case BackpressureDropOldest:
+		return "drop oldest"
+	case BackpressureDropNewest:
+		return "drop newest"
+	default:
+		return "unknown"
+	}
+}
+
+//ReceiverDrops counts the frames of each type a Receiver dropped because of its backpressure policies, and the status
+//events it dropped because the Status channel was full.
+type ReceiverDrops struct {
+	Video, Audio, Metadata, Status uint64
+}
+
+type receiverOptions struct {
+	frames     ReceiverFrames
+	bufferSize int
+
+	videoPolicy, audioPolicy, metadataPolicy BackpressurePolicy
+
+	onVideo    func(*CapturedVideoFrame)
+	onAudio    func(*CapturedAudioFrame)
+	onMetadata func(*CapturedMetadata)
+	onStatus   func(ReceiverStatus)
+}
+
+//ReceiverOption configures a Receiver.
+type ReceiverOption func(o *receiverOptions)
+
+//Limits the receiver to the given types of frames, it captures all of them by default. Frames of other types are
+//dropped by the runtime.
+func ReceiveFrames(frames ReceiverFrames) ReceiverOption {
+	return func(o *receiverOptions) {
+		o.frames = frames
+	}
+}
+
+//Sets how many frames of each type the channels hold before the backpressure policy applies, 4 by default.
+func ReceiveBufferSize(n int) ReceiverOption {
+	return func(o *receiverOptions) {
+		o.bufferSize = n
+	}
+}
+
+//Sets the policy for the channels of the given types of frames, all of them block by default. Channels with a policy
+//that drops frames hold at least one frame.
+func ReceiveBackpressure(frames ReceiverFrames, policy BackpressurePolicy) ReceiverOption {
+	return func(o *receiverOptions) {
+		if frames&ReceiveVideo != 0 {
+			o.videoPolicy = policy
+		}
+		if frames&ReceiveAudio != 0 {
+			o.audioPolicy = policy
+		}
+		if frames&ReceiveMetadata != 0 {
+			o.metadataPolicy = policy
+		}
+	}
+}
+
+//Hands video frames to fn on the capture goroutine instead of the Video channel. Frames handed to callbacks are never
+//dropped, capturing waits for fn to return. fn takes over the frame and must release it.
+func ReceiveVideoFunc(fn func(*CapturedVideoFrame)) ReceiverOption {
+	return func(o *receiverOptions) {
+		o.onVideo = fn
+	}
+}
+
+//Hands audio frames to fn on the capture goroutine instead of the Audio channel.
+func ReceiveAudioFunc(fn func(*CapturedAudioFrame)) ReceiverOption {
+	return func(o *receiverOptions) {
+		o.onAudio = fn
+	}
+}
+
+//Hands metadata frames to fn on the capture goroutine instead of the Metadata channel.
+func ReceiveMetadataFunc(fn func(*CapturedMetadata)) ReceiverOption {
+	return func(o *receiverOptions) {
+		o.onMetadata = fn
+	}
+}
+
+//Hands status events to fn on the capture goroutine instead of the Status channel.
+func ReceiveStatusFunc(fn func(ReceiverStatus)) ReceiverOption {
+	return func(o *receiverOptions) {
+		o.onStatus = fn
+	}
+}
+
+//Receiver captures frames from a RecvInstance on its own goroutine and delivers them by type on channels or to
+//callbacks. Frames are delivered as captured by the runtime, without copying their data. Whoever receives a frame owns
+//it and must release it, Clone makes a copy that Go owns. The frames the receiver drops are released by it.
+type Receiver struct {
+	recv   *RecvInstance
+	opts   receiverOptions
+	cancel context.CancelFunc
+	worker
+
+	video    chan *CapturedVideoFrame
+	audio    chan *CapturedAudioFrame
+	metadata chan *CapturedMetadata
+	status   chan ReceiverStatus
+
+	droppedVideo, droppedAudio, droppedMetadata, droppedStatus atomic.Uint64
+}
+
+//Returns a channel for a type of frame along with a callback that sends to it according to the policy, counting the
+//frames it drops and handing them to release if it is not nil. A blocked send gives up once ctx is done.
+func newQueue[T any](ctx context.Context, size int, policy BackpressurePolicy, dropped *atomic.Uint64, release func(T) error) (chan T, func(T)) {
+	drop := func(v T) {
+		if release != nil {
+			release(v)
+		}
+	}
+
+	switch policy {
+	case BackpressureDropOldest:
+		c := make(chan T, max(size, 1))
+		return c, func(v T) {
+			for {
+				select {
+				case c <- v:
+					return
+				default:
+				}
+
+				select {
+				case old := <-c:
+					dropped.Add(1)
+					drop(old)
+				default:
+				}
+			}
+		}
+	case BackpressureDropNewest:
+		c := make(chan T, max(size, 1))
+		return c, func(v T) {
+			select {
+			case c <- v:
+			default:
+				dropped.Add(1)
+				drop(v)
+			}
+		}
+	default:
+		c := make(chan T, size)
+		return c, func(v T) {
+			select {
+			case c <- v:
+			case <-ctx.Done():
+				drop(v)
+			}
+		}
+	}
+}
+
+//Releases the frames left in a queue that nobody received and closes it.
+func closeQueue[T any](c chan T, release func(T) error) {
+	if c == nil {
+		return
+	}
+
+	for done := false; !done; {
+		select {
+		case v := <-c:
+			release(v)
+		default:
+			done = true
+		}
+	}
+	close(c)
+}
+
+//NewReceiver starts capturing from recv until ctx is done or Close is called. The channels of the selected frame
+//types that have no callback must be drained. The Status channel drops its oldest event when it is full, so it can be
+//left alone. The receive instance stays owned by the caller. Destroying it while the receiver runs waits for the capture in progress and then stops the
+//receiver with ErrClosed.
+func NewReceiver(ctx context.Context, recv *RecvInstance, opts ...ReceiverOption) *Receiver {
+	o := receiverOptions{frames: ReceiveAll, bufferSize: 4}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Receiver{recv: recv, cancel: cancel, worker: newWorker()}
+
+	if o.frames&ReceiveVideo != 0 && o.onVideo == nil {
+		r.video, o.onVideo = newQueue(ctx, o.bufferSize, o.videoPolicy, &r.droppedVideo, (*CapturedVideoFrame).Release)
+	}
+	if o.frames&ReceiveAudio != 0 && o.onAudio == nil {
+		r.audio, o.onAudio = newQueue(ctx, o.bufferSize, o.audioPolicy, &r.droppedAudio, (*CapturedAudioFrame).Release)
+	}
+	if o.frames&ReceiveMetadata != 0 && o.onMetadata == nil {
+		r.metadata, o.onMetadata = newQueue(ctx, o.bufferSize, o.metadataPolicy, &r.droppedMetadata, (*CapturedMetadata).Release)
+	}
+	if o.onStatus == nil {
+		r.status, o.onStatus = newQueue[ReceiverStatus](ctx, o.bufferSize, BackpressureDropOldest, &r.droppedStatus, nil)
+	}
+
+	r.opts = o
+	go r.run(ctx)
+	return r
+}
+
+//Video returns the channel video frames are delivered on, it is closed when the receiver stops and the frames nobody
+//received by then are released. It is nil when video is not captured or goes to a callback.
+func (r *Receiver) Video() <-chan *CapturedVideoFrame {
+	return r.video
+}
+
+//Audio returns the channel audio frames are delivered on, like Video.
+func (r *Receiver) Audio() <-chan *CapturedAudioFrame {
+	return r.audio
+}
+
+//Metadata returns the channel metadata frames are delivered on, like Video.
+func (r *Receiver) Metadata() <-chan *CapturedMetadata {
+	return r.metadata
+}
+
+//Status returns the channel status events are delivered on, like Video.
+func (r *Receiver) Status() <-chan ReceiverStatus {
+	return r.status
+}
+
+//Dropped returns how many frames of each type were dropped so far because of the backpressure policies.
+func (r *Receiver) Dropped() ReceiverDrops {
+	return ReceiverDrops{r.droppedVideo.Load(), r.droppedAudio.Load(), r.droppedMetadata.Load(), r.droppedStatus.Load()}
+}
+
+//Close stops the receiver and waits for it, the channels are closed when it returns. It does not destroy the receive
+//instance.
+func (r *Receiver) Close() error {
+	r.cancel()
+	<-r.done
+	return nil
+}
+
+func (r *Receiver) run(ctx context.Context) {
+	err := r.capture(ctx)
+
+	closeQueue(r.video, (*CapturedVideoFrame).Release)
+	closeQueue(r.audio, (*CapturedAudioFrame).Release)
+	closeQueue(r.metadata, (*CapturedMetadata).Release)
+	if r.status != nil {
+		close(r.status)
+	}
+	r.stop(err)
+}
+
+func (r *Receiver) capture(ctx context.Context) error {
+	var (
+		vf VideoFrameV2
+		af AudioFrameV2
+		mf MetadataFrame
+
+		//The frames to capture into, nil for the types that are not captured.
+		vp *VideoFrameV2
+		ap *AudioFrameV2
+		mp *MetadataFrame
+	)
+	if r.opts.frames&ReceiveVideo != 0 {
+		vp = &vf
+	}
+	if r.opts.frames&ReceiveAudio != 0 {
+		ap = &af
+	}
+	if r.opts.frames&ReceiveMetadata != 0 {
+		mp = &mf
+	}
+
+	//Status events are only sent when the connection is lost or made again, not for every capture that fails.
+	connected := true
+	for {
+		ft, err := r.recv.CaptureV2Context(ctx, vp, ap, mp, receiverCaptureTimeout)
+		if errors.Is(err, ErrNotConnected) {
+			if connected {
+				connected = false
+				r.opts.onStatus(ReceiverConnectionLost)
+			}
+
+			t := time.NewTimer(receiverRetryDelay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !connected && ft != FrameTypeNone {
+			connected = true
+			r.opts.onStatus(ReceiverReconnected)
+		}
+
+		switch ft {
+		case FrameTypeVideo:
+			r.opts.onVideo(NewCapturedVideoFrame(r.recv, &vf))
+		case FrameTypeAudio:
+			r.opts.onAudio(NewCapturedAudioFrame(r.recv, &af))
+		case FrameTypeMetadata:
+			r.opts.onMetadata(NewCapturedMetadata(r.recv, &mf))
+		case FrameTypeStatusChange:
+			r.opts.onStatus(ReceiverStatusChanged)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+//Creates a receive instance connected to a source added to the fake backend.
+func newFakeRecv(t *testing.T, fake *FakeBackend, pool *ObjectPool, name string) *RecvInstance {
+	fake.AddSource(name, "10.0.0.1:5961")
+
+	settings := NewRecvCreateSettings()
+	settings.SourceToConnectTo = *SourceInfo{Name: name}.Source()
+	recv := must(NewRecvInstanceV2(settings))
+	pool.Register(recv)
+	return recv
+}
+
+func fakeMetadata(s string) FakeFrame {
+	data := []byte(s + "\x00")
+	return FakeFrame{Type: FrameTypeMetadata, Metadata: &MetadataFrame{Data: &data[0]}}
+}
+
+func TestReceiver(t *testing.T) {
+	fake, _, recv := withFakeRecv(t, "CAMERA (1)")
+
+	var audio []*CapturedAudioFrame
+	r := NewReceiver(context.Background(), recv,
+		ReceiveFrames(ReceiveVideo|ReceiveMetadata),
+		ReceiveAudioFunc(func(af *CapturedAudioFrame) { audio = append(audio, af) }),
+	)
+	if r.Audio() != nil {
+		t.Error("Expected no audio channel when audio is not captured.")
+	}
+
+	fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeAudio, Audio: NewAudioFrameV2()})
+	fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeVideo, Video: &VideoFrameV2{Xres: 16, Yres: 9}})
+	fake.Deliver("CAMERA (1)", fakeMetadata("<hello/>"))
+
+	select {
+	case f := <-r.Video():
+		if vf := must(f.Frame()); vf.Xres != 16 || vf.Yres != 9 {
+			t.Errorf("Unexpected video frame %+v.", vf)
+		}
+		checkErr(t, f.Release())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the video frame.")
+	}
+
+	select {
+	case f := <-r.Metadata():
+		if s := goStringFromPtr(must(f.Frame()).Data); s != "<hello/>" {
+			t.Errorf("Unexpected metadata %q.", s)
+		}
+		checkErr(t, f.Release())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the metadata.")
+	}
+
+	checkErr(t, r.Close())
+	if !errors.Is(r.Wait(), context.Canceled) {
+		t.Errorf("Expected the receiver to be canceled, got %v.", r.Wait())
+	}
+	if _, ok := <-r.Video(); ok {
+		t.Error("Expected the video channel to be closed.")
+	}
+	if _, ok := <-r.Status(); ok {
+		t.Error("Expected the status channel to be closed.")
+	}
+	if len(audio) != 0 {
+		t.Errorf("Expected audio to be dropped, got %d frames.", len(audio))
+	}
+	if n := fake.CapturedFrames(); n != 0 {
+		t.Errorf("Expected every frame to be released, %d are not.", n)
+	}
+}
+
+func TestReceiverClosedInstance(t *testing.T) {
+	_, _, recv := withFakeRecv(t, "CAMERA (1)")
+
+	//Closing the instance while the receiver waits in a capture must wait for the capture, the fake backend fails
+	//destroying a receiver that a capture is still using.
+	r := NewReceiver(context.Background(), recv)
+	time.Sleep(50 * time.Millisecond)
+	checkErr(t, recv.Close())
+
+	select {
+	case <-r.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the receiver to stop.")
+	}
+	if !errors.Is(r.Wait(), ErrClosed) {
+		t.Errorf("Expected the receiver to stop with ErrClosed, got %v.", r.Wait())
+	}
+}
+
+func TestReceiverConnectionLost(t *testing.T) {
+	fake, _, recv := withFakeRecv(t, "CAMERA (1)")
+
+	r := NewReceiver(context.Background(), recv, ReceiveFrames(ReceiveVideo))
+	defer r.Close()
+
+	nextStatus := func() ReceiverStatus {
+		select {
+		case s := <-r.Status():
+			return s
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a status event.")
+			return 0
+		}
+	}
+
+	fake.RemoveSource("CAMERA (1)")
+	if s := nextStatus(); s != ReceiverConnectionLost {
+		t.Fatalf("Expected the connection to be lost, got %v.", s)
+	}
+
+	//Capturing keeps failing while the source is gone, which must neither repeat the event nor fill the channel.
+	time.Sleep(5 * receiverRetryDelay)
+	fake.AddSource("CAMERA (1)", "10.0.0.2:5961")
+	fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeVideo, Video: &VideoFrameV2{Xres: 16, Yres: 9}})
+
+	select {
+	case f := <-r.Video():
+		if vf := must(f.Frame()); vf.Xres != 16 {
+			t.Errorf("Unexpected video frame %+v.", vf)
+		}
+		checkErr(t, f.Release())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the video frame after reconnecting.")
+	}
+	if s := nextStatus(); s != ReceiverReconnected {
+		t.Errorf("Expected the connection to be made again, got %v.", s)
+	}
+	if n := len(r.Status()); n != 0 {
+		t.Errorf("Expected no more status events, %d are queued.", n)
+	}
+	if d := r.Dropped(); d != (ReceiverDrops{}) {
+		t.Errorf("Unexpected drop counts %+v.", d)
+	}
+}
+
+func TestReceiverBackpressure(t *testing.T) {
+	fake, _, recv := withFakeRecv(t, "CAMERA (1)")
+
+	r := NewReceiver(context.Background(), recv,
+		ReceiveBufferSize(2),
+		ReceiveBackpressure(ReceiveVideo, BackpressureDropNewest),
+		ReceiveBackpressure(ReceiveMetadata, BackpressureDropOldest),
+	)
+	defer r.Close()
+
+	for i := int32(0); i < 5; i++ {
+		fake.Deliver("CAMERA (1)", fakeMetadata(string(rune('a'+i))))
+		fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeVideo, Video: &VideoFrameV2{Xres: i}})
+	}
+	for i := int32(0); i < 3; i++ {
+		fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeAudio, Audio: &AudioFrameV2{NumSamples: i}})
+	}
+
+	//The third audio frame blocks capturing until the consumer makes room.
+	for deadline := time.Now().Add(5 * time.Second); len(r.Audio()) != 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the frames to be captured, dropped %+v.", r.Dropped())
+		}
+	}
+	if d := r.Dropped(); d != (ReceiverDrops{Video: 3, Metadata: 3}) {
+		t.Errorf("Unexpected drop counts %+v.", d)
+	}
+
+	for _, expected := range []int32{0, 1} {
+		f := <-r.Video()
+		if vf := must(f.Frame()); vf.Xres != expected {
+			t.Errorf("Expected video frame %d to be kept, got %d.", expected, vf.Xres)
+		}
+		checkErr(t, f.Release())
+	}
+	for _, expected := range []string{"d", "e"} {
+		f := <-r.Metadata()
+		if s := goStringFromPtr(must(f.Frame()).Data); s != expected {
+			t.Errorf("Expected metadata %q to be kept, got %q.", expected, s)
+		}
+		checkErr(t, f.Release())
+	}
+
+	//The last audio frame is left for the receiver to release when it stops, like the frames it dropped.
+	for _, expected := range []int32{0, 1} {
+		f := <-r.Audio()
+		if af := must(f.Frame()); af.NumSamples != expected {
+			t.Errorf("Expected audio frame %d, got %d.", expected, af.NumSamples)
+		}
+		checkErr(t, f.Release())
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(r.Audio()) != 1; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the last audio frame to be queued.")
+		}
+	}
+
+	checkErr(t, r.Close())
+	if n := fake.CapturedFrames(); n != 0 {
+		t.Errorf("Expected every frame to be released, %d are not.", n)
+	}
+}
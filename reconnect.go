@@ -0,0 +1,319 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//How often a reconnecting receiver checks its connection while the sources do not change.
+const reconnectPollInterval = 250 * time.Millisecond
+
+//How long a reconnecting receiver leaves an instance without a connection while its source is online before it
+//recreates the instance.
+const reconnectStallTimeout = 2 * time.Second
+
+type ConnectionEventType int
+
+const (
+	SourceConnected ConnectionEventType = iota
+	SourceDisconnected
+)
+
+func (t ConnectionEventType) String() string {
+	switch t {
+	case SourceConnected:
+		return "connected"
+	case SourceDisconnected:
+		return "disconnected"
+	default:
+		return "unknown"
+	}
+}
+
+type ConnectionEvent struct {
+	Type ConnectionEventType
+
+	//The source the receive instance was created for.
+	Source SourceInfo
+
+	//Set on SourceConnected events that follow a SourceDisconnected event.
+	Reconnect bool
+}
+
+//ReconnectingReceiver keeps a receive instance connected to the source with a given name on its own goroutine. When
+//the source comes back at a different address, or stays listed without the instance connecting to it, the instance is
+//recreated and the tally and connection metadata set through the ReconnectingReceiver are applied to it again.
+type ReconnectingReceiver struct {
+	find     *FindInstance
+	name     string
+	settings RecvCreateSettings
+	events   chan ConnectionEvent
+	cancel   context.CancelFunc
+	worker
+
+	//Held for reading while the instance is used and for writing while it is replaced.
+	recvMu  sync.RWMutex
+	recv    *RecvInstance
+	source  SourceInfo
+	stopped bool
+
+	mu       sync.Mutex
+	tally    *Tally
+	metadata []*MetadataFrame
+}
+
+//NewReconnectingReceiver starts following the source with the given name as seen by find until ctx is done or Close is
+//called. The instances are created with a copy of settings, or with the defaults if it is nil, connected to the
+//source. Events are delivered on the Events channel, which must be drained. The finder stays owned by the caller and
+//must outlive the receiver.
+func NewReconnectingReceiver(ctx context.Context, find *FindInstance, name string, settings *RecvCreateSettings) (*ReconnectingReceiver, error) {
+	if err := validateName("source name", name, maxSourceNameLen); err != nil {
+		return nil, err
+	}
+
+	if settings == nil {
+		settings = NewRecvCreateSettings()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &ReconnectingReceiver{
+		find:     find,
+		name:     name,
+		settings: *settings,
+		events:   make(chan ConnectionEvent, 16),
+		cancel:   cancel,
+		worker:   newWorker(),
+	}
+
+	go r.run(ctx)
+	return r, nil
+}
+
+//Events returns the channel events are delivered on, it is closed when the receiver stops.
+func (r *ReconnectingReceiver) Events() <-chan ConnectionEvent {
+	return r.events
+}
+
+//Use calls fn with the current receive instance, which is not replaced until fn returns. Frames captured inside fn must
+//be freed inside fn. It returns ErrNotConnected while the source has not been found yet and ErrClosed once the
+//receiver has stopped. fn must not call methods of the receiver.
+func (r *ReconnectingReceiver) Use(fn func(recv *RecvInstance) error) error {
+	r.recvMu.RLock()
+	defer r.recvMu.RUnlock()
+
+	switch {
+	case r.stopped:
+		return ErrClosed
+	case r.recv == nil:
+		return ErrNotConnected
+	}
+	return fn(r.recv)
+}
+
+//Source returns the source the current receive instance was created for.
+func (r *ReconnectingReceiver) Source() SourceInfo {
+	r.recvMu.RLock()
+	defer r.recvMu.RUnlock()
+	return r.source
+}
+
+//SetTally sets the tally of the current instance and of every instance that replaces it. Unlike
+//RecvInstance.SetTally it does not fail when not connected, the tally is sent as soon as the connection is made.
+func (r *ReconnectingReceiver) SetTally(tally *Tally) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := *tally
+	r.tally = &t
+	err := r.Use(func(recv *RecvInstance) error {
+		return recv.SetTally(&t)
+	})
+	if errors.Is(err, ErrNotConnected) {
+		return nil
+	}
+	return err
+}
+
+//AddConnectionMetadata adds connection metadata to the current instance and to every instance that replaces it.
+func (r *ReconnectingReceiver) AddConnectionMetadata(mf *MetadataFrame) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := cloneMetadataFrame(mf)
+	r.metadata = append(r.metadata, c)
+	err := r.Use(func(recv *RecvInstance) error {
+		return recv.AddConnectionMetadata(c)
+	})
+	if errors.Is(err, ErrNotConnected) {
+		return nil
+	}
+	return err
+}
+
+//ClearConnectionMetadata clears the connection metadata of the current instance and of every instance that replaces
+//it.
+func (r *ReconnectingReceiver) ClearConnectionMetadata() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metadata = nil
+	err := r.Use(func(recv *RecvInstance) error {
+		return recv.ClearConnectionMetadata()
+	})
+	if errors.Is(err, ErrNotConnected) {
+		return nil
+	}
+	return err
+}
+
+//Close stops the receiver and waits for it to destroy the receive instance.
+func (r *ReconnectingReceiver) Close() error {
+	r.cancel()
+	<-r.done
+	return nil
+}
+
+func (r *ReconnectingReceiver) run(ctx context.Context) {
+	err := r.watch(ctx, func(ev ConnectionEvent) bool {
+		select {
+		case r.events <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	r.recvMu.Lock()
+	if r.recv != nil {
+		if destroyErr := r.recv.Destroy(); err == nil {
+			err = destroyErr
+		}
+		r.recv = nil
+	}
+	r.stopped = true
+	r.recvMu.Unlock()
+
+	close(r.events)
+	r.stop(err)
+}
+
+//Returns the current instance, the source it was created for and its number of connections.
+func (r *ReconnectingReceiver) current() (*RecvInstance, SourceInfo, int, error) {
+	r.recvMu.RLock()
+	recv, source := r.recv, r.source
+	r.recvMu.RUnlock()
+
+	if recv == nil {
+		return nil, source, 0, nil
+	}
+
+	n, err := recv.GetNumConnections(0)
+	return recv, source, n, err
+}
+
+func (r *ReconnectingReceiver) watch(ctx context.Context, emit func(ConnectionEvent) bool) error {
+	var (
+		connected, everConnected bool
+		stalledSince             time.Time
+	)
+	for {
+		sources, err := r.find.CurrentSources()
+		if err != nil {
+			return err
+		}
+		src, found := SourceRef{Name: r.name}.Match(sources)
+
+		recv, current, n, err := r.current()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if n > 0 {
+			stalledSince = time.Time{}
+		} else if stalledSince.IsZero() {
+			stalledSince = now
+		}
+
+		if found && (recv == nil || src.Address != current.Address || n == 0 && now.Sub(stalledSince) >= reconnectStallTimeout) {
+			//Failing to create the instance is retried on the next round.
+			if err := r.recreate(src); err != nil && !errors.Is(err, ErrCreateFailed) {
+				return err
+			}
+			stalledSince = now
+
+			if _, current, n, err = r.current(); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case n > 0 && !connected:
+			if !emit(ConnectionEvent{Type: SourceConnected, Source: current, Reconnect: everConnected}) {
+				return nil
+			}
+			connected, everConnected = true, true
+		case n == 0 && connected:
+			if !emit(ConnectionEvent{Type: SourceDisconnected, Source: current}) {
+				return nil
+			}
+			connected = false
+		}
+
+		if _, err := r.find.WaitForSourcesContext(ctx, reconnectPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+//Replaces the receive instance with one connected to src that has the tally and connection metadata applied.
+func (r *ReconnectingReceiver) recreate(src SourceInfo) error {
+	settings := r.settings
+	settings.SourceToConnectTo = *src.Source()
+	recv, err := NewRecvInstanceV2(&settings)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.applyLocked(recv); err != nil {
+		recv.Destroy()
+		return err
+	}
+
+	r.recvMu.Lock()
+	old := r.recv
+	r.recv, r.source = recv, src
+	r.recvMu.Unlock()
+
+	if old != nil {
+		return old.Destroy()
+	}
+	return nil
+}
+
+func (r *ReconnectingReceiver) applyLocked(recv *RecvInstance) error {
+	for _, mf := range r.metadata {
+		if err := recv.AddConnectionMetadata(mf); err != nil {
+			return err
+		}
+	}
+
+	if r.tally != nil {
+		if err := recv.SetTally(r.tally); err != nil && !errors.Is(err, ErrNotConnected) {
+			return err
+		}
+	}
+	return nil
+}
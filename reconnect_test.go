@@ -0,0 +1,89 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func nextConnectionEvent(t *testing.T, r *ReconnectingReceiver) ConnectionEvent {
+	select {
+	case ev := <-r.Events():
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a connection event.")
+		return ConnectionEvent{}
+	}
+}
+
+func TestReconnectingReceiver(t *testing.T) {
+	fake, pool := withFake(t)
+
+	find := must(NewFindInstanceV2(pool.NewFindCreateSettings(true, "", "")))
+	pool.Register(find)
+
+	r := must(NewReconnectingReceiver(context.Background(), find, "FAKE (camera)", nil))
+	defer r.Close()
+
+	//Tally and connection metadata set before the source is found are applied once it is.
+	checkErr(t, r.SetTally(&Tally{OnProgram: true}))
+	metadata := []byte("<ndi_format/>\x00")
+	checkErr(t, r.AddConnectionMetadata(&MetadataFrame{Data: &metadata[0]}))
+	if err := r.Use(func(*RecvInstance) error { return nil }); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("Expected no instance before the source is found, got %v.", err)
+	}
+
+	send := must(NewSendInstance(pool.NewSendCreateSettings("camera", "", true, false)))
+	if ev := nextConnectionEvent(t, r); ev.Type != SourceConnected || ev.Reconnect || ev.Source.Name != "FAKE (camera)" {
+		t.Fatalf("Expected the first connection, got %+v.", ev)
+	}
+
+	var first *RecvInstance
+	checkErr(t, r.Use(func(recv *RecvInstance) error {
+		first = recv
+		return nil
+	}))
+
+	checkErr(t, send.Close())
+	if ev := nextConnectionEvent(t, r); ev.Type != SourceDisconnected {
+		t.Fatalf("Expected the connection to be lost, got %+v.", ev)
+	}
+
+	//The sender comes back at a different address.
+	send = must(NewSendInstance(pool.NewSendCreateSettings("camera", "", true, false)))
+	pool.Register(send)
+	ev := nextConnectionEvent(t, r)
+	if ev.Type != SourceConnected || !ev.Reconnect || ev.Source != r.Source() {
+		t.Fatalf("Expected a reconnection, got %+v.", ev)
+	}
+
+	checkErr(t, r.Use(func(recv *RecvInstance) error {
+		if recv == first {
+			t.Error("Expected the instance to be recreated for the new address.")
+		}
+		return nil
+	}))
+
+	var tally Tally
+	must(send.GetTally(&tally, 0))
+	if !tally.OnProgram {
+		t.Errorf("Expected the tally to be applied again, got %+v.", tally)
+	}
+	if m := fake.ConnectionMetadata("FAKE (camera)"); !reflect.DeepEqual(m, []string{"<ndi_format/>"}) {
+		t.Errorf("Expected the connection metadata to be applied again, got %v.", m)
+	}
+
+	checkErr(t, r.Close())
+	if err := r.Use(func(*RecvInstance) error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected the instance to be destroyed, got %v.", err)
+	}
+	if !errors.Is(r.Wait(), context.Canceled) {
+		t.Errorf("Expected the receiver to be canceled, got %v.", r.Wait())
+	}
+}
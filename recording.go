@@ -0,0 +1,163 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	recordingNotSupportedErr = fmt.Errorf("source does not support recording: %w", ErrNotSupported)
+	notRecordingErr          = errors.New("source is not recording")
+)
+
+//RecordingTimes tells how far a recording got.
+type RecordingTimes struct {
+	//The number of video frames recorded.
+	Frames int64
+
+	//The times of the first and of the latest frame in the recording.
+	Start, Last time.Time
+}
+
+//Duration returns how long the recording is so far.
+func (t RecordingTimes) Duration() time.Duration {
+	return t.Last.Sub(t.Start)
+}
+
+//Returns the time of a timestamp of the runtime, in UTC at 100ns intervals since the Unix epoch.
+func timeFrom100ns(t int64) time.Time {
+	return time.Unix(t/1e7, t%1e7*100)
+}
+
+//Recording controls the recording that the source a receive instance is connected to makes of itself. Like PTZ it
+//checks that the source supports recording before every call, asking the runtime again after a status change, and
+//returns an error wrapping ErrNotSupported when it does not.
+type Recording struct {
+	recv      *RecvInstance
+	supported recvCapability
+}
+
+//Recording returns a controller for the recording of the source the receive instance is connected to. The controller
+//is only valid as long as the receive instance is.
+func (inst *RecvInstance) Recording() *Recording {
+	return &Recording{recv: inst}
+}
+
+//IsSupported returns whether the source supports recording. This is only known once connected.
+func (r *Recording) IsSupported() (bool, error) {
+	return r.supported.check(r.recv, Backend.RecvRecordingIsSupported)
+}
+
+//Checks that the source supports recording and sends the command with fn.
+func (r *Recording) command(fn func(b Backend, inst Handle) (bool, error)) error {
+	return r.recv.command(&r.supported, Backend.RecvRecordingIsSupported, recordingNotSupportedErr, fn)
+}
+
+//Checks that the source supports recording and calls fn with the library locked for reading.
+func (r *Recording) locked(fn func(b Backend, inst Handle) error) error {
+	ok, err := r.IsSupported()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return recordingNotSupportedErr
+	}
+
+	b, err := r.recv.rlock()
+	if err != nil {
+		return err
+	}
+	defer r.recv.runlock()
+	return fn(b, r.recv.handle)
+}
+
+//Returns a copy of a string handed out by the runtime and frees it, the empty string when there is none.
+func (r *Recording) string(get func(b Backend, inst Handle) (*byte, error)) (string, error) {
+	var s string
+	err := r.locked(func(b Backend, inst Handle) error {
+		p, err := get(b, inst)
+		if err != nil || p == nil {
+			return err
+		}
+
+		s = goStringFromPtr(p)
+		return b.RecvFreeString(inst, p)
+	})
+	return s, err
+}
+
+//Start starts recording. The source picks the name of the file, using filenameHint as a starting point when it is not
+//empty, Filename returns the name it picked.
+func (r *Recording) Start(filenameHint string) error {
+	if strings.IndexByte(filenameHint, 0) >= 0 {
+		return fmt.Errorf("%w: filename hint %q contains a NULL character", ErrInvalidSettings, filenameHint)
+	}
+
+	hint := cStringPtr(filenameHint)
+	return r.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvRecordingStart(inst, hint)
+	})
+}
+
+//Stop stops recording.
+func (r *Recording) Stop() error {
+	return r.command(Backend.RecvRecordingStop)
+}
+
+//SetAudioLevel sets the level in dB that the audio is recorded at, 0 records it unchanged.
+func (r *Recording) SetAudioLevel(levelDB float32) error {
+	if math.IsNaN(float64(levelDB)) || math.IsInf(float64(levelDB), 0) {
+		return fmt.Errorf("%w: audio level %v is not finite", ErrOutOfRange, levelDB)
+	}
+	return r.command(func(b Backend, inst Handle) (bool, error) {
+		return b.RecvRecordingSetAudioLevel(inst, levelDB)
+	})
+}
+
+//IsRecording returns whether the source is recording.
+func (r *Recording) IsRecording() (bool, error) {
+	var recording bool
+	err := r.locked(func(b Backend, inst Handle) (err error) {
+		recording, err = b.RecvRecordingIsRecording(inst)
+		return err
+	})
+	return recording, err
+}
+
+//Filename returns the name of the file that is or was last recorded to, the empty string when there is none.
+func (r *Recording) Filename() (string, error) {
+	return r.string(Backend.RecvRecordingGetFilename)
+}
+
+//ErrorText returns the error the recording ran into, the empty string when there is none.
+func (r *Recording) ErrorText() (string, error) {
+	return r.string(Backend.RecvRecordingGetError)
+}
+
+//Times returns how far the recording got, it returns an error when the source is not recording.
+func (r *Recording) Times() (RecordingTimes, error) {
+	var times RecvRecordingTime
+	err := r.locked(func(b Backend, inst Handle) error {
+		ok, err := b.RecvRecordingGetTimes(inst, &times)
+		if err == nil && !ok {
+			err = notRecordingErr
+		}
+		return err
+	})
+	if err != nil {
+		return RecordingTimes{}, err
+	}
+
+	return RecordingTimes{
+		Frames: times.NoFrames,
+		Start:  timeFrom100ns(times.StartTime),
+		Last:   timeFrom100ns(times.LastTime),
+	}, nil
+}
@@ -0,0 +1,72 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecording(t *testing.T) {
+	fake, _, recv := withFakeRecv(t, "CAMERA (1)")
+	rec := recv.Recording()
+
+	if err := rec.Start("take"); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported before the source supports recording, got %v.", err)
+	}
+
+	fake.SetRecordingSupported("CAMERA (1)", true)
+	captureStatusChange(t, recv)
+
+	if name := must(rec.Filename()); name != "" {
+		t.Errorf("Expected no file name before recording, got %q.", name)
+	}
+	if _, err := rec.Times(); err == nil {
+		t.Error("Expected getting the times to fail before recording.")
+	}
+
+	checkErr(t, rec.SetAudioLevel(-6))
+	checkErr(t, rec.Start("take"))
+	if !must(rec.IsRecording()) {
+		t.Error("Expected the source to be recording.")
+	}
+
+	fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeVideo, Video: &VideoFrameV2{Xres: 16, Yres: 9}})
+	fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeVideo, Video: &VideoFrameV2{Xres: 16, Yres: 9}})
+
+	times := must(rec.Times())
+	if times.Frames != 2 {
+		t.Errorf("Expected 2 frames recorded, got %d.", times.Frames)
+	}
+	if since := time.Since(times.Start); since < 0 || since > time.Minute {
+		t.Errorf("Unexpected recording start %v.", times.Start)
+	}
+	if times.Duration() < 0 {
+		t.Errorf("Unexpected recording duration %v.", times.Duration())
+	}
+
+	if name := must(rec.Filename()); name != "take.mov" {
+		t.Errorf("Unexpected file name %q.", name)
+	}
+
+	fake.SetRecordingError("CAMERA (1)", "disk full")
+	if text := must(rec.ErrorText()); text != "disk full" {
+		t.Errorf("Unexpected error text %q.", text)
+	}
+
+	checkErr(t, rec.Stop())
+	if state, _ := fake.RecordingState("CAMERA (1)"); state.Recording || state.AudioLevel != -6 {
+		t.Errorf("Unexpected recorder state %+v.", state)
+	}
+
+	if n := fake.AllocatedStrings(); n != 0 {
+		t.Errorf("Expected every string to be freed, %d were not.", n)
+	}
+
+	if err := rec.Start("bad\x00name"); !errors.Is(err, ErrInvalidSettings) {
+		t.Errorf("Expected a filename hint with a NULL character to be rejected, got %v.", err)
+	}
+}
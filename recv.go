@@ -5,85 +5,284 @@
 package ndi
 
 import (
-	"syscall"
-	"unsafe"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-type RecvInstance struct{}
+type RecvInstance struct {
+	instance
+	conn connectionMetadata
 
-func NewRecvInstanceV2(settings *RecvCreateSettings) *RecvInstance {
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibRecvCreateV2, 1, uintptr(unsafe.Pointer(settings)), 0, 0)
-	if eno != 0 {
-		panic(eno)
+	//The number of FrameTypeStatusChange frames captured so far.
+	statusChanges atomic.Uint64
+}
+
+func NewRecvInstanceV2(settings *RecvCreateSettings) (*RecvInstance, error) {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return nil, err
+	}
+	defer l.mu.RUnlock()
+
+	h, err := b.RecvCreateV2(settings)
+	if err != nil {
+		return nil, err
 	}
-	return (*RecvInstance)(unsafe.Pointer(ret))
+
+	if h == 0 {
+		return nil, ErrCreateFailed
+	}
+
+	inst := &RecvInstance{instance: instance{lib: l, handle: h}}
+	trackLeak(inst)
+	return inst, nil
 }
 
-func (inst *RecvInstance) Destroy() {
-	if _, _, eno := syscall.Syscall(funcPtrs.NDIlibRecvDestroy, 1, uintptr(unsafe.Pointer(inst)), 0, 0); eno != 0 {
-		panic(eno)
+func (inst *RecvInstance) Destroy() error {
+	untrackLeak(inst)
+
+	b, err := inst.lockClose()
+	if b == nil {
+		return err
 	}
+	defer inst.unlockClose()
+	defer inst.conn.reset()
+	return b.RecvDestroy(inst.handle)
 }
 
-//Set the up-stream tally notifications. This returns FALSE if we are not currently connected to anything. That
+//Close is Destroy, it makes the instance an io.Closer that can be registered with an ObjectPool.
+func (inst *RecvInstance) Close() error {
+	return inst.Destroy()
+}
+
+//Set the up-stream tally notifications. This returns ErrNotConnected if we are not currently connected to anything. That
 //said, the moment that we do connect to something it will automatically be sent the tally state.
-func (inst *RecvInstance) SetTally(tally *Tally) bool {
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibRecvSetTally, 2, uintptr(unsafe.Pointer(inst)), uintptr(unsafe.Pointer(tally)), 0)
-	if eno != 0 {
-		panic(eno)
+func (inst *RecvInstance) SetTally(tally *Tally) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+
+	ok, err := b.RecvSetTally(inst.handle, tally)
+	if err == nil && !ok {
+		err = ErrNotConnected
 	}
-	return ret != 0
+	return err
 }
 
-//This function will send a meta message to the source that we are connected too. This returns FALSE if we are
+//This function will send a meta message to the source that we are connected too. This returns ErrNotConnected if we are
 //not currently connected to anything.
-func (inst *RecvInstance) SendMetadata(mf *MetadataFrame) bool {
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibRecvSendMetadata, 2, uintptr(unsafe.Pointer(inst)), uintptr(unsafe.Pointer(mf)), 0)
-	if eno != 0 {
-		panic(eno)
+func (inst *RecvInstance) SendMetadata(mf *MetadataFrame) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
 	}
-	return ret != 0
+	defer inst.runlock()
+
+	ok, err := b.RecvSendMetadata(inst.handle, mf)
+	if err == nil && !ok {
+		err = ErrNotConnected
+	}
+	return err
 }
 
-func (inst *RecvInstance) CaptureV2(vf *VideoFrameV2, af *AudioFrameV2, mf *MetadataFrame, timeoutInMs uint32) FrameType {
-	ret, _, _ := syscall.Syscall6(
-		funcPtrs.NDIlibRecvCaptureV2,
-		5,
-		uintptr(unsafe.Pointer(inst)),
-		uintptr(unsafe.Pointer(vf)),
-		uintptr(unsafe.Pointer(af)),
-		uintptr(unsafe.Pointer(mf)),
-		uintptr(timeoutInMs),
-		0,
-	)
+//This will allow you to receive video, audio and metadata frames. Any of the frames can be nil, in which case data of
+//that type will not be captured in this call. FrameTypeNone is returned when nothing arrived within the timeout, and
+//FrameTypeError together with ErrNotConnected when the connection was lost.
+func (inst *RecvInstance) CaptureV2(vf *VideoFrameV2, af *AudioFrameV2, mf *MetadataFrame, timeoutInMs uint32) (FrameType, error) {
+	b, err := inst.rlock()
+	if err != nil {
+		return FrameTypeError, err
+	}
+	defer inst.runlock()
 
-	return FrameType(ret)
+	ft, err := b.RecvCaptureV2(inst.handle, vf, af, mf, timeoutInMs)
+	if err == nil && ft == FrameTypeError {
+		err = ErrNotConnected
+	}
+	if ft == FrameTypeStatusChange {
+		inst.statusChanges.Add(1)
+	}
+	return ft, err
+}
+
+//CaptureV2Context is CaptureV2 that returns ctx.Err() as soon as ctx is done. A frame that arrived is always returned,
+//even when ctx is done at the same time, so that it can be freed.
+func (inst *RecvInstance) CaptureV2Context(ctx context.Context, vf *VideoFrameV2, af *AudioFrameV2, mf *MetadataFrame, timeout time.Duration) (FrameType, error) {
+	return waitContext(ctx, timeout, func(timeoutInMs uint32) (FrameType, bool, error) {
+		ft, err := inst.CaptureV2(vf, af, mf, timeoutInMs)
+		return ft, ft != FrameTypeNone, err
+	})
 }
 
-func (inst *RecvInstance) FreeVideoV2(vf *VideoFrameV2) {
-	if _, _, eno := syscall.Syscall(funcPtrs.NDIlibRecvFreeVideoV2, 2, uintptr(unsafe.Pointer(inst)), uintptr(unsafe.Pointer(vf)), 0); eno != 0 {
-		panic(eno)
+func (inst *RecvInstance) FreeVideoV2(vf *VideoFrameV2) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
 	}
+	defer inst.runlock()
+	return b.RecvFreeVideoV2(inst.handle, vf)
 }
 
-func (inst *RecvInstance) FreeAudioV2(af *AudioFrameV2) {
-	if _, _, eno := syscall.Syscall(funcPtrs.NDIlibRecvFreeAudioV2, 2, uintptr(unsafe.Pointer(inst)), uintptr(unsafe.Pointer(af)), 0); eno != 0 {
-		panic(eno)
+func (inst *RecvInstance) FreeAudioV2(af *AudioFrameV2) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
 	}
+	defer inst.runlock()
+	return b.RecvFreeAudioV2(inst.handle, af)
 }
 
-func (inst *RecvInstance) FreeMetadataV2(mf *MetadataFrame) {
-	if _, _, eno := syscall.Syscall(funcPtrs.NDIlibRecvFreeMetadata, 2, uintptr(unsafe.Pointer(inst)), uintptr(unsafe.Pointer(mf)), 0); eno != 0 {
-		panic(eno)
+func (inst *RecvInstance) FreeMetadataV2(mf *MetadataFrame) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
 	}
+	defer inst.runlock()
+	return b.RecvFreeMetadata(inst.handle, mf)
 }
 
 //Is this receiver currently connected to a source on the other end, or has the source not yet been found or is no longe ronline.
 //This will normally return 0 or 1.
 func (inst *RecvInstance) GetNumConnections(timeoutInMs uint32) (int, error) {
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibRecvGetNoConnections, 2, uintptr(unsafe.Pointer(inst)), uintptr(timeoutInMs), 0)
-	if eno != 0 {
-		return 0, Error{eno}
+	b, err := inst.rlock()
+	if err != nil {
+		return 0, err
+	}
+	defer inst.runlock()
+	return b.RecvGetNoConnections(inst.handle, timeoutInMs)
+}
+
+//GetNumConnectionsContext waits until the receiver is connected, the timeout elapses or ctx is done, in which case it
+//returns ctx.Err().
+func (inst *RecvInstance) GetNumConnectionsContext(ctx context.Context, timeout time.Duration) (int, error) {
+	return waitContext(ctx, timeout, func(timeoutInMs uint32) (int, bool, error) {
+		n, err := inst.GetNumConnections(timeoutInMs)
+		return n, n > 0, err
+	})
+}
+
+//Get the current performance structures. This can be used to determine if you have been calling CaptureV2 fast
+//enough, or if your processing of data is not keeping up with real-time. The total structure will give you the total
+//frame counts received, the dropped structure will tell you how many frames have been dropped. Either can be nil.
+func (inst *RecvInstance) GetPerformance(total, dropped *RecvPerformance) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return b.RecvGetPerformance(inst.handle, total, dropped)
+}
+
+//This will allow you to determine the current queue depth for all of the frame sources at any time.
+func (inst *RecvInstance) GetQueue(total *RecvQueue) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return b.RecvGetQueue(inst.handle, total)
+}
+
+//Clears the connection metadata that is sent to every source this receiver connects to.
+func (inst *RecvInstance) ClearConnectionMetadata() error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return inst.conn.clear(func() error {
+		return b.RecvClearConnectionMetadata(inst.handle)
+	})
+}
+
+//Adds metadata that is sent to every source this receiver connects to, now and on every future connection. This is how
+//a receiver tells a source about itself, for instance what format it would prefer. A copy of the frame is kept until
+//the metadata is cleared or the instance is destroyed, mf can be reused right away.
+func (inst *RecvInstance) AddConnectionMetadata(mf *MetadataFrame) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return inst.conn.add(mf, func(mf *MetadataFrame) error {
+		return b.RecvAddConnectionMetadata(inst.handle, mf)
+	})
+}
+
+//AddConnectionMetadataString is AddConnectionMetadata for an XML string, which must be well-formed.
+func (inst *RecvInstance) AddConnectionMetadataString(s string) error {
+	mf, err := metadataFrameFromString(s)
+	if err != nil {
+		return err
+	}
+	return inst.AddConnectionMetadata(mf)
+}
+
+//AddConnectionMetadataXML is AddConnectionMetadata for the XML encoding of v, see encoding/xml.
+func (inst *RecvInstance) AddConnectionMetadataXML(v any) error {
+	mf, err := metadataFrameFromXML(v)
+	if err != nil {
+		return err
+	}
+	return inst.AddConnectionMetadata(mf)
+}
+
+//Remembers whether the source of a receive instance has a capability such as PTZ, which the runtime learns about once
+//connected and then reports with a FrameTypeStatusChange frame. A capability that is supported is asked about again
+//only after the next status change, one that is not on every check.
+type recvCapability struct {
+	mu            sync.Mutex
+	supported     bool
+	statusChanges uint64
+}
+
+func (c *recvCapability) check(recv *RecvInstance, query func(b Backend, inst Handle) (bool, error)) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := recv.statusChanges.Load()
+	if c.supported && n == c.statusChanges {
+		return true, nil
+	}
+
+	b, err := recv.rlock()
+	if err != nil {
+		return false, err
+	}
+	defer recv.runlock()
+
+	ok, err := query(b, recv.handle)
+	if err != nil {
+		return false, err
+	}
+	c.supported, c.statusChanges = ok, n
+	return ok, nil
+}
+
+//Checks the capability, returning notSupported when the source does not have it, and sends the command with fn.
+//Commands the runtime could not send return ErrNotConnected.
+func (inst *RecvInstance) command(c *recvCapability, query func(b Backend, inst Handle) (bool, error), notSupported error, fn func(b Backend, inst Handle) (bool, error)) error {
+	ok, err := c.check(inst, query)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return notSupported
+	}
+
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+
+	ok, err = fn(b, inst.handle)
+	if err == nil && !ok {
+		err = ErrNotConnected
 	}
-	return int(ret), nil
+	return err
 }
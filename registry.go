@@ -0,0 +1,228 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+//The longest a registry goes without refreshing the last-seen times of the sources that are online.
+const registryPollInterval = time.Second
+
+//RegistryEntry is what a Registry knows about one source.
+type RegistryEntry struct {
+	Source    SourceInfo
+	FirstSeen time.Time
+
+	//The last time the source was in the list of the finder.
+	LastSeen time.Time
+
+	//False while the source is missing from the list of the finder but still inside the grace period.
+	Online bool
+}
+
+type registryEntry struct {
+	RegistryEntry
+
+	//When the source was first found missing from the list of the finder.
+	missingSince time.Time
+}
+
+//Registry keeps track of the sources a finder sees on its own goroutine. Sources that disappear are only removed once
+//they have been missing for the grace period, so that sources that flicker in and out of the list stay put.
+type Registry struct {
+	find  *FindInstance
+	grace time.Duration
+	worker
+
+	mu      sync.Mutex
+	entries map[string]*registryEntry
+	subs    map[int]func(SourceEvent)
+	nextSub int
+}
+
+//NewRegistry starts tracking the sources of find until ctx is done. The finder stays owned by the caller and must
+//outlive the registry.
+func NewRegistry(ctx context.Context, find *FindInstance, grace time.Duration) (*Registry, error) {
+	sources, err := find.CurrentSources()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Registry{
+		find:    find,
+		grace:   grace,
+		worker:  newWorker(),
+		entries: make(map[string]*registryEntry),
+		subs:    make(map[int]func(SourceEvent)),
+	}
+
+	r.update(sources, time.Now())
+	go r.run(ctx)
+	return r, nil
+}
+
+//Subscribe calls fn on the registry goroutine for every source that is added, changes its address or is removed
+//after the grace period. Use Entries for the sources that are known already. The returned function unsubscribes.
+func (r *Registry) Subscribe(fn func(SourceEvent)) (unsubscribe func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.nextSub
+	r.nextSub++
+	r.subs[id] = fn
+
+	return func() {
+		r.mu.Lock()
+		delete(r.subs, id)
+		r.mu.Unlock()
+	}
+}
+
+//Entries returns every known source sorted by name, including the ones inside their grace period.
+func (r *Registry) Entries() []RegistryEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]RegistryEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		entries = append(entries, e.RegistryEntry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Source.Name < entries[j].Source.Name
+	})
+	return entries
+}
+
+//Lookup returns the entry of the source with the given name.
+func (r *Registry) Lookup(name string) (RegistryEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[name]
+	if !ok {
+		return RegistryEntry{}, false
+	}
+	return e.RegistryEntry, true
+}
+
+//Match returns the entries of the sources whose names m matches, sorted by name.
+func (r *Registry) Match(m SourceMatcher) []RegistryEntry {
+	var matched []RegistryEntry
+	for _, e := range r.Entries() {
+		if m.MatchName(e.Source.Name) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+func (r *Registry) run(ctx context.Context) {
+	r.stop(r.watch(ctx))
+}
+
+func (r *Registry) watch(ctx context.Context) error {
+	for {
+		wait := registryPollInterval
+		if next, ok := r.nextExpiry(); ok {
+			wait = max(min(wait, time.Until(next)), 0)
+		}
+
+		if _, err := r.find.WaitForSourcesContext(ctx, wait); err != nil {
+			return err
+		}
+
+		sources, err := r.find.CurrentSources()
+		if err != nil {
+			return err
+		}
+
+		events := r.update(sources, time.Now())
+		r.mu.Lock()
+		subs := make([]func(SourceEvent), 0, len(r.subs))
+		for _, fn := range r.subs {
+			subs = append(subs, fn)
+		}
+		r.mu.Unlock()
+
+		for _, ev := range events {
+			for _, fn := range subs {
+				fn(ev)
+			}
+		}
+	}
+}
+
+//Returns when the first source inside its grace period is due to be removed.
+func (r *Registry) nextExpiry() (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var next time.Time
+	for _, e := range r.entries {
+		if e.Online {
+			continue
+		}
+		if t := e.missingSince.Add(r.grace); next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	return next, !next.IsZero()
+}
+
+//Merges the current list of sources into the registry and returns what changed.
+func (r *Registry) update(sources []SourceInfo, now time.Time) []SourceEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []SourceEvent
+	seen := make(map[string]bool, len(sources))
+	for _, s := range sources {
+		seen[s.Name] = true
+
+		e, ok := r.entries[s.Name]
+		if !ok {
+			r.entries[s.Name] = &registryEntry{RegistryEntry: RegistryEntry{s, now, now, true}}
+			events = append(events, SourceEvent{Type: SourceAdded, Source: s})
+			continue
+		}
+
+		if e.Source.Address != s.Address {
+			events = append(events, SourceEvent{Type: SourceChanged, Source: s, Previous: e.Source})
+			e.Source = s
+		}
+		e.LastSeen = now
+		e.Online = true
+	}
+
+	var removed []SourceInfo
+	for name, e := range r.entries {
+		if seen[name] {
+			continue
+		}
+
+		if e.Online {
+			e.Online = false
+			e.missingSince = now
+		}
+
+		if now.Sub(e.missingSince) >= r.grace {
+			delete(r.entries, name)
+			removed = append(removed, e.Source)
+		}
+	}
+
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].Name < removed[j].Name
+	})
+	for _, s := range removed {
+		events = append(events, SourceEvent{Type: SourceRemoved, Source: s})
+	}
+	return events
+}
@@ -0,0 +1,97 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistryUpdate(t *testing.T) {
+	r := &Registry{grace: time.Second, entries: make(map[string]*registryEntry)}
+	camera := SourceInfo{"CAMERA (1)", "10.0.0.1:5961"}
+	start := time.Now()
+
+	if events := r.update([]SourceInfo{camera}, start); len(events) != 1 || events[0].Type != SourceAdded {
+		t.Fatalf("Expected the source to be added, got %v.", events)
+	}
+
+	//Missing for less than the grace period only marks the source offline.
+	if events := r.update(nil, start.Add(time.Second)); len(events) != 0 {
+		t.Fatalf("Expected no events inside the grace period, got %v.", events)
+	}
+	if e, ok := r.Lookup(camera.Name); !ok || e.Online || !e.LastSeen.Equal(start) {
+		t.Fatalf("Unexpected entry %+v inside the grace period.", e)
+	}
+
+	if events := r.update([]SourceInfo{camera}, start.Add(1500*time.Millisecond)); len(events) != 0 {
+		t.Fatalf("Expected the source to come back silently, got %v.", events)
+	}
+	if e, _ := r.Lookup(camera.Name); !e.Online || !e.FirstSeen.Equal(start) {
+		t.Fatalf("Unexpected entry %+v after the source came back.", e)
+	}
+
+	r.update(nil, start.Add(2*time.Second))
+	if events := r.update(nil, start.Add(3*time.Second)); len(events) != 1 || events[0] != (SourceEvent{Type: SourceRemoved, Source: camera}) {
+		t.Fatalf("Expected the source to be removed after the grace period, got %v.", events)
+	}
+	if _, ok := r.Lookup(camera.Name); ok {
+		t.Error("The removed source is still in the registry.")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	fake, pool := withFake(t)
+	fake.AddSource("CAMERA (1)", "10.0.0.1:5961")
+
+	find := must(NewFindInstanceV2(pool.NewFindCreateSettings(true, "", "")))
+	pool.Register(find)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := must(NewRegistry(ctx, find, 50*time.Millisecond))
+
+	if entries := r.Entries(); len(entries) != 1 || !entries[0].Online {
+		t.Fatalf("Unexpected initial entries %v.", entries)
+	}
+
+	events := make(chan SourceEvent, 16)
+	unsubscribe := r.Subscribe(func(ev SourceEvent) { events <- ev })
+	defer unsubscribe()
+
+	//A blip shorter than the grace period goes unnoticed.
+	fake.RemoveSource("CAMERA (1)")
+	fake.AddSource("CAMERA (1)", "10.0.0.1:5961")
+	fake.AddSource("CAMERA (2)", "10.0.0.2:5961")
+
+	select {
+	case ev := <-events:
+		if ev.Type != SourceAdded || ev.Source.Name != "CAMERA (2)" {
+			t.Fatalf("Expected the second source to be added, got %v.", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the second source.")
+	}
+
+	fake.RemoveSource("CAMERA (1)")
+	select {
+	case ev := <-events:
+		if ev.Type != SourceRemoved || ev.Source.Name != "CAMERA (1)" {
+			t.Fatalf("Expected the first source to be removed, got %v.", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the removal.")
+	}
+
+	if matched := r.Match(MatchExact(StreamName, "2")); len(matched) != 1 {
+		t.Errorf("Expected one match, got %v.", matched)
+	}
+
+	cancel()
+	if err := r.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v.", err)
+	}
+}
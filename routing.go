@@ -0,0 +1,68 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+//A router is a source on the network that forwards whatever source it is currently switched to. Receivers connected
+//to the router follow the switch without having to reconnect.
+type RoutingInstance struct {
+	instance
+}
+
+func NewRoutingInstance(settings *RoutingCreateSettings) (*RoutingInstance, error) {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return nil, err
+	}
+	defer l.mu.RUnlock()
+
+	h, err := b.RoutingCreate(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	if h == 0 {
+		return nil, ErrCreateFailed
+	}
+
+	inst := &RoutingInstance{instance: instance{lib: l, handle: h}}
+	trackLeak(inst)
+	return inst, nil
+}
+
+func (inst *RoutingInstance) Destroy() error {
+	untrackLeak(inst)
+
+	b, err := inst.lockClose()
+	if b == nil {
+		return err
+	}
+	defer inst.unlockClose()
+	return b.RoutingDestroy(inst.handle)
+}
+
+//Close is Destroy, it makes the instance an io.Closer that can be registered with an ObjectPool.
+func (inst *RoutingInstance) Close() error {
+	return inst.Destroy()
+}
+
+//Change the routing of this source to another destination.
+func (inst *RoutingInstance) Change(source *Source) (bool, error) {
+	b, err := inst.rlock()
+	if err != nil {
+		return false, err
+	}
+	defer inst.runlock()
+	return b.RoutingChange(inst.handle, source)
+}
+
+//Clear the routing, receivers connected to this source will no longer receive anything.
+func (inst *RoutingInstance) Clear() (bool, error) {
+	b, err := inst.rlock()
+	if err != nil {
+		return false, err
+	}
+	defer inst.runlock()
+	return b.RoutingClear(inst.handle)
+}
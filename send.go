@@ -5,40 +5,201 @@
 package ndi
 
 import (
-	"syscall"
-	"unsafe"
+	"context"
+	"time"
 )
 
-type SendInstance struct{}
+type SendInstance struct {
+	instance
+	conn connectionMetadata
+}
+
+func NewSendInstance(settings *SendCreateSettings) (*SendInstance, error) {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return nil, err
+	}
+	defer l.mu.RUnlock()
 
-func NewSendInstance(settings *SendCreateSettings) *SendInstance {
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibSendCreate, 1, uintptr(unsafe.Pointer(settings)), 0, 0)
-	if eno != 0 {
-		panic(eno)
+	h, err := b.SendCreate(settings)
+	if err != nil {
+		return nil, err
 	}
-	return (*SendInstance)(unsafe.Pointer(ret))
+
+	if h == 0 {
+		return nil, ErrCreateFailed
+	}
+
+	inst := &SendInstance{instance: instance{lib: l, handle: h}}
+	trackLeak(inst)
+	return inst, nil
 }
 
-func (inst *SendInstance) Destroy() {
-	if _, _, eno := syscall.Syscall(funcPtrs.NDIlibSendDestroy, 1, uintptr(unsafe.Pointer(inst)), 0, 0); eno != 0 {
-		panic(eno)
+func (inst *SendInstance) Destroy() error {
+	untrackLeak(inst)
+
+	b, err := inst.lockClose()
+	if b == nil {
+		return err
 	}
+	defer inst.unlockClose()
+	defer inst.conn.reset()
+	return b.SendDestroy(inst.handle)
+}
+
+//Close is Destroy, it makes the instance an io.Closer that can be registered with an ObjectPool.
+func (inst *SendInstance) Close() error {
+	return inst.Destroy()
 }
 
 //This will add a video frame.
-func (inst *SendInstance) SendVideoV2(frame *VideoFrameV2) {
-	if _, _, eno := syscall.Syscall(funcPtrs.NDIlibSendSendVideoV2, 2, uintptr(unsafe.Pointer(inst)), uintptr(unsafe.Pointer(frame)), 0); eno != 0 {
-		panic(eno)
+func (inst *SendInstance) SendVideoV2(frame *VideoFrameV2) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return b.SendSendVideoV2(inst.handle, frame)
+}
+
+//This will add a video frame and will return immediately, having scheduled the frame to be displayed. All processing
+//and sending of the video will occur asynchronously. The memory accessed by the frame must remain valid until the next
+//call to SendVideoAsyncV2 or a synchronizing call such as SendVideoV2 or Destroy.
+func (inst *SendInstance) SendVideoAsyncV2(frame *VideoFrameV2) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return b.SendSendVideoAsyncV2(inst.handle, frame)
+}
+
+//This will add an audio frame.
+func (inst *SendInstance) SendAudioV2(frame *AudioFrameV2) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return b.SendSendAudioV2(inst.handle, frame)
+}
+
+//This will add a metadata frame.
+func (inst *SendInstance) SendMetadata(frame *MetadataFrame) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return b.SendSendMetadata(inst.handle, frame)
+}
+
+//This allows you to receive metadata from the other end of the connection. Frames returned as FrameTypeMetadata
+//must be freed with FreeMetadata.
+func (inst *SendInstance) Capture(mf *MetadataFrame, timeoutInMs uint32) (FrameType, error) {
+	b, err := inst.rlock()
+	if err != nil {
+		return FrameTypeError, err
+	}
+	defer inst.runlock()
+	return b.SendCapture(inst.handle, mf, timeoutInMs)
+}
+
+//Free the buffers returned by Capture for metadata.
+func (inst *SendInstance) FreeMetadata(mf *MetadataFrame) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return b.SendFreeMetadata(inst.handle, mf)
+}
+
+//Determine the current tally state. If you specify a timeout then it will wait until it has changed, otherwise it
+//will simply poll it and return the current tally immediately. The return value is whether anything has actually
+//changed (true) or whether it timed out (false).
+func (inst *SendInstance) GetTally(tally *Tally, timeoutInMs uint32) (bool, error) {
+	b, err := inst.rlock()
+	if err != nil {
+		return false, err
 	}
+	defer inst.runlock()
+	return b.SendGetTally(inst.handle, tally, timeoutInMs)
 }
 
 //Get the current number of receivers connected to this source. This can be used to avoid even rendering when nothing is connected to the video source.
 //which can significantly improve the efficiency if you want to make a lot of sources available on the network. If you specify a timeout that is not
 //0 then it will wait until there are connections for this amount of time.
 func (inst *SendInstance) GetNumConnections(timeoutInMs uint32) (int, error) {
-	ret, _, eno := syscall.Syscall(funcPtrs.NDIlibSendGetNoConnections, 2, uintptr(unsafe.Pointer(inst)), uintptr(timeoutInMs), 0)
-	if eno != 0 {
-		return 0, Error{eno}
+	b, err := inst.rlock()
+	if err != nil {
+		return 0, err
+	}
+	defer inst.runlock()
+	return b.SendGetNoConnections(inst.handle, timeoutInMs)
+}
+
+//GetNumConnectionsContext waits until a receiver is connected, the timeout elapses or ctx is done, in which case it
+//returns ctx.Err().
+func (inst *SendInstance) GetNumConnectionsContext(ctx context.Context, timeout time.Duration) (int, error) {
+	return waitContext(ctx, timeout, func(timeoutInMs uint32) (int, bool, error) {
+		n, err := inst.GetNumConnections(timeoutInMs)
+		return n, n > 0, err
+	})
+}
+
+//This will assign a new fail-over source for this video source. What this means is that if this video source was to
+//fail any receivers would automatically switch over to use this source, unless this source then came back online.
+//You can specify nil to clear the source.
+func (inst *SendInstance) SetFailover(source *Source) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return b.SendSetFailover(inst.handle, source)
+}
+
+//Clears the connection metadata that is sent to every receiver this sender connects to.
+func (inst *SendInstance) ClearConnectionMetadata() error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return inst.conn.clear(func() error {
+		return b.SendClearConnectionMetadata(inst.handle)
+	})
+}
+
+//Adds metadata that is sent to every receiver this sender connects to, now and on every future connection. This is how
+//a sender tells a receiver about itself, for instance its product name. A copy of the frame is kept until the metadata
+//is cleared or the instance is destroyed, mf can be reused right away.
+func (inst *SendInstance) AddConnectionMetadata(mf *MetadataFrame) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return inst.conn.add(mf, func(mf *MetadataFrame) error {
+		return b.SendAddConnectionMetadata(inst.handle, mf)
+	})
+}
+
+//AddConnectionMetadataString is AddConnectionMetadata for an XML string, which must be well-formed.
+func (inst *SendInstance) AddConnectionMetadataString(s string) error {
+	mf, err := metadataFrameFromString(s)
+	if err != nil {
+		return err
+	}
+	return inst.AddConnectionMetadata(mf)
+}
+
+//AddConnectionMetadataXML is AddConnectionMetadata for the XML encoding of v, see encoding/xml.
+func (inst *SendInstance) AddConnectionMetadataXML(v any) error {
+	mf, err := metadataFrameFromXML(v)
+	if err != nil {
+		return err
 	}
-	return int(ret), nil
+	return inst.AddConnectionMetadata(mf)
 }
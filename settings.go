@@ -0,0 +1,157 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//The longest source and group names the builders accept, in bytes.
+const (
+	maxSourceNameLen = 253
+	maxGroupNameLen  = 63
+)
+
+func validateName(what, s string, maxLen int) error {
+	if s == "" {
+		return fmt.Errorf("%w: %s is empty", ErrInvalidSettings, what)
+	}
+
+	if len(s) > maxLen {
+		return fmt.Errorf("%w: %s %q is longer than %d bytes", ErrInvalidSettings, what, s, maxLen)
+	}
+
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("%w: %s %q is not valid UTF-8", ErrInvalidSettings, what, s)
+	}
+
+	if strings.TrimSpace(s) != s {
+		return fmt.Errorf("%w: %s %q starts or ends with white space", ErrInvalidSettings, what, s)
+	}
+
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%w: %s %q contains a control character", ErrInvalidSettings, what, s)
+		}
+	}
+	return nil
+}
+
+//Validates the groups and joins them into the comma separated list the runtime expects.
+func joinGroups(groups []string) (string, error) {
+	for _, g := range groups {
+		if err := validateName("group name", g, maxGroupNameLen); err != nil {
+			return "", err
+		}
+
+		if strings.Contains(g, ",") {
+			return "", fmt.Errorf("%w: group name %q contains a comma", ErrInvalidSettings, g)
+		}
+	}
+	return strings.Join(groups, ","), nil
+}
+
+type findOptions struct {
+	showLocalSources bool
+	groups           []string
+	extraIPs         []netip.Addr
+}
+
+//FindOption configures the settings built by BuildFindCreateSettings.
+type FindOption func(o *findOptions)
+
+//Sets whether sources running on this machine are found, they are by default.
+func FindShowLocalSources(show bool) FindOption {
+	return func(o *findOptions) {
+		o.showLocalSources = show
+	}
+}
+
+//Limits the finder to sources in the given groups instead of the default groups.
+func FindGroups(groups ...string) FindOption {
+	return func(o *findOptions) {
+		o.groups = append(o.groups, groups...)
+	}
+}
+
+//Adds machines to query for sources directly, for networks where discovery does not reach them.
+func FindExtraIPs(ips ...netip.Addr) FindOption {
+	return func(o *findOptions) {
+		o.extraIPs = append(o.extraIPs, ips...)
+	}
+}
+
+//Validates the options and creates settings for NewFindInstanceV2 that are owned by the pool.
+func (p *ObjectPool) BuildFindCreateSettings(opts ...FindOption) (*FindCreateSettings, error) {
+	o := findOptions{showLocalSources: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	groups, err := joinGroups(o.groups)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]string, len(o.extraIPs))
+	for i, ip := range o.extraIPs {
+		if !ip.IsValid() {
+			return nil, fmt.Errorf("%w: extra ip %d is the zero address", ErrInvalidSettings, i)
+		}
+		ips[i] = ip.String()
+	}
+	return p.NewFindCreateSettings(o.showLocalSources, groups, strings.Join(ips, ",")), nil
+}
+
+type sendOptions struct {
+	groups                 []string
+	clockVideo, clockAudio bool
+}
+
+//SendOption configures the settings built by BuildSendCreateSettings.
+type SendOption func(o *sendOptions)
+
+//Puts the source in the given groups instead of the default groups.
+func SendGroups(groups ...string) SendOption {
+	return func(o *sendOptions) {
+		o.groups = append(o.groups, groups...)
+	}
+}
+
+//Sets whether sending video blocks to keep frames at their frame rate, it does by default.
+func SendClockVideo(clock bool) SendOption {
+	return func(o *sendOptions) {
+		o.clockVideo = clock
+	}
+}
+
+//Sets whether sending audio blocks to keep it at its sample rate, it does by default.
+func SendClockAudio(clock bool) SendOption {
+	return func(o *sendOptions) {
+		o.clockAudio = clock
+	}
+}
+
+//Validates the name and options and creates settings for NewSendInstance that are owned by the pool.
+func (p *ObjectPool) BuildSendCreateSettings(name string, opts ...SendOption) (*SendCreateSettings, error) {
+	o := sendOptions{clockVideo: true, clockAudio: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := validateName("source name", name, maxSourceNameLen); err != nil {
+		return nil, err
+	}
+
+	groups, err := joinGroups(o.groups)
+	if err != nil {
+		return nil, err
+	}
+	return p.NewSendCreateSettings(name, groups, o.clockVideo, o.clockAudio), nil
+}
@@ -0,0 +1,74 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestBuildFindCreateSettings(t *testing.T) {
+	pool := NewObjectPool()
+	defer pool.CloseAll()
+
+	s, err := pool.BuildFindCreateSettings(
+		FindShowLocalSources(false),
+		FindGroups("studio", "public"),
+		FindExtraIPs(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.showLocalSources || goStringFromPtr(s.groups) != "studio,public" || goStringFromPtr(s.extraIPs) != "10.0.0.1,10.0.0.2" {
+		t.Errorf("Unexpected settings %v, %q, %q.", s.showLocalSources, goStringFromPtr(s.groups), goStringFromPtr(s.extraIPs))
+	}
+
+	s, err = pool.BuildFindCreateSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.showLocalSources || s.groups != nil || s.extraIPs != nil {
+		t.Error("Default settings do not leave everything to the runtime.")
+	}
+
+	invalid := [][]FindOption{
+		{FindGroups("")},
+		{FindGroups("a,b")},
+		{FindGroups(" studio")},
+		{FindGroups(strings.Repeat("g", maxGroupNameLen+1))},
+		{FindExtraIPs(netip.Addr{})},
+	}
+	for _, opts := range invalid {
+		if _, err := pool.BuildFindCreateSettings(opts...); !errors.Is(err, ErrInvalidSettings) {
+			t.Errorf("Expected ErrInvalidSettings, got %v.", err)
+		}
+	}
+}
+
+func TestBuildSendCreateSettings(t *testing.T) {
+	pool := NewObjectPool()
+	defer pool.CloseAll()
+
+	s, err := pool.BuildSendCreateSettings("Camera 1", SendGroups("studio"), SendClockAudio(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if goStringFromPtr(s.ndiName) != "Camera 1" || goStringFromPtr(s.groups) != "studio" || !s.clockVideo || s.clockAudio {
+		t.Errorf("Unexpected settings %q, %q, %v, %v.", goStringFromPtr(s.ndiName), goStringFromPtr(s.groups), s.clockVideo, s.clockAudio)
+	}
+
+	for _, name := range []string{"", "Camera\x001", "Camera\n1", "\xff", strings.Repeat("n", maxSourceNameLen+1)} {
+		if _, err := pool.BuildSendCreateSettings(name); !errors.Is(err, ErrInvalidSettings) {
+			t.Errorf("Expected ErrInvalidSettings for %q, got %v.", name, err)
+		}
+	}
+	if _, err := pool.BuildSendCreateSettings("Camera 1", SendGroups("a,b")); !errors.Is(err, ErrInvalidSettings) {
+		t.Errorf("Expected ErrInvalidSettings, got %v.", err)
+	}
+}
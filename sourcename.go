@@ -0,0 +1,155 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"regexp"
+	"strings"
+)
+
+//SourceName is a source name split into the name of the machine the source runs on and the name of the stream, NDI
+//formats them as "MACHINE (Stream)".
+type SourceName struct {
+	Machine, Stream string
+}
+
+//Splits a source name into its components. Names that are not of the form "MACHINE (Stream)" are returned whole as
+//the machine name, with ok set to false.
+func ParseSourceName(name string) (n SourceName, ok bool) {
+	i := strings.Index(name, " (")
+	if i < 0 || !strings.HasSuffix(name, ")") {
+		return SourceName{Machine: name}, false
+	}
+	return SourceName{name[:i], name[i+2 : len(name)-1]}, true
+}
+
+//Returns the name in the canonical "MACHINE (Stream)" form.
+func (n SourceName) String() string {
+	if n.Stream == "" {
+		return n.Machine
+	}
+	return n.Machine + " (" + n.Stream + ")"
+}
+
+func (s *Source) ParsedName() SourceName {
+	n, _ := ParseSourceName(s.Name())
+	return n
+}
+
+func (si SourceInfo) ParsedName() SourceName {
+	n, _ := ParseSourceName(si.Name)
+	return n
+}
+
+//NameComponent selects the part of a source name a matcher looks at.
+type NameComponent int
+
+const (
+	FullName NameComponent = iota
+	MachineName
+	StreamName
+)
+
+func (c NameComponent) of(n SourceName) string {
+	switch c {
+	case MachineName:
+		return n.Machine
+	case StreamName:
+		return n.Stream
+	default:
+		return n.String()
+	}
+}
+
+//SourceMatcher reports whether a source name is one that is looked for.
+type SourceMatcher func(n SourceName) bool
+
+//Matches names whose component is exactly s.
+func MatchExact(c NameComponent, s string) SourceMatcher {
+	return func(n SourceName) bool {
+		return c.of(n) == s
+	}
+}
+
+//Matches names whose component is s under Unicode case-folding.
+func MatchFold(c NameComponent, s string) SourceMatcher {
+	return func(n SourceName) bool {
+		return strings.EqualFold(c.of(n), s)
+	}
+}
+
+//Matches names whose whole component matches the glob pattern, in which '*' matches any run of characters and '?'
+//matches a single character.
+func MatchGlob(c NameComponent, pattern string) SourceMatcher {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+
+	re := regexp.MustCompile(sb.String())
+	return func(n SourceName) bool {
+		return re.MatchString(c.of(n))
+	}
+}
+
+//Matches names whose component contains a match of the regular expression expr.
+func MatchRegexp(c NameComponent, expr string) (SourceMatcher, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(n SourceName) bool {
+		return re.MatchString(c.of(n))
+	}, nil
+}
+
+//Matches names that all of the matchers match.
+func MatchAll(ms ...SourceMatcher) SourceMatcher {
+	return func(n SourceName) bool {
+		for _, m := range ms {
+			if !m(n) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func (m SourceMatcher) MatchName(name string) bool {
+	n, _ := ParseSourceName(name)
+	return m(n)
+}
+
+//Returns the sources from GetCurrentSources that match.
+func (m SourceMatcher) FilterSources(sources []*Source) []*Source {
+	var matched []*Source
+	for _, s := range sources {
+		if m.MatchName(s.Name()) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+//Returns the sources from CurrentSources that match.
+func (m SourceMatcher) Filter(sources []SourceInfo) []SourceInfo {
+	var matched []SourceInfo
+	for _, s := range sources {
+		if m.MatchName(s.Name) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,70 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import "testing"
+
+func TestParseSourceName(t *testing.T) {
+	cases := []struct {
+		name string
+		want SourceName
+		ok   bool
+	}{
+		{"STUDIO-PC (Camera 1)", SourceName{"STUDIO-PC", "Camera 1"}, true},
+		{"STUDIO-PC (Camera (wide))", SourceName{"STUDIO-PC", "Camera (wide)"}, true},
+		{"STUDIO-PC ()", SourceName{"STUDIO-PC", ""}, true},
+		{"STUDIO-PC", SourceName{"STUDIO-PC", ""}, false},
+		{"STUDIO-PC (Camera 1", SourceName{"STUDIO-PC (Camera 1", ""}, false},
+	}
+
+	for _, c := range cases {
+		n, ok := ParseSourceName(c.name)
+		if n != c.want || ok != c.ok {
+			t.Errorf("Parsing %q gave %#v, %t. Expected %#v, %t.", c.name, n, ok, c.want, c.ok)
+		}
+	}
+
+	if s := (SourceName{"STUDIO-PC", "Camera 1"}).String(); s != "STUDIO-PC (Camera 1)" {
+		t.Errorf("Unexpected canonical name %q.", s)
+	}
+}
+
+func TestSourceMatchers(t *testing.T) {
+	sources := []SourceInfo{
+		{Name: "STUDIO-PC (Camera 1)"},
+		{Name: "STUDIO-PC (Camera 2)"},
+		{Name: "studio-mac (Slides)"},
+		{Name: "OFFICE (Camera 1)"},
+	}
+
+	re, err := MatchRegexp(StreamName, `^Camera \d$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MatchRegexp(StreamName, `(`); err == nil {
+		t.Error("An invalid expression was accepted.")
+	}
+
+	cases := []struct {
+		matcher SourceMatcher
+		want    int
+	}{
+		{MatchExact(FullName, "STUDIO-PC (Camera 1)"), 1},
+		{MatchExact(MachineName, "STUDIO-PC"), 2},
+		{MatchFold(MachineName, "studio-pc"), 2},
+		{MatchFold(StreamName, "slides"), 1},
+		{MatchGlob(MachineName, "STUDIO-*"), 2},
+		{MatchGlob(FullName, "* (Camera ?)"), 3},
+		{MatchGlob(StreamName, "Camera"), 0},
+		{re, 3},
+		{MatchAll(re, MatchExact(MachineName, "OFFICE")), 1},
+	}
+
+	for i, c := range cases {
+		if n := len(c.matcher.Filter(sources)); n != c.want {
+			t.Errorf("Matcher %d selected %d sources, expected %d.", i, n, c.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//SourceRef identifies a source in a form that can be saved and resolved to the live source later, unlike Source it
+//holds no runtime memory. It marshals to a JSON object and to text of the form "name=...&address=...&group=...".
+type SourceRef struct {
+	Name string
+
+	//Where the source was last seen, used to find it again when it has been renamed.
+	Address string
+
+	//The group the source was found in, if not one of the default groups. Use it to create a finder that sees the
+	//source, resolving does not look at it.
+	Group string
+}
+
+//Ref returns a reference to the source that was found in the given group, empty for the default groups.
+func (si SourceInfo) Ref(group string) SourceRef {
+	return SourceRef{si.Name, si.Address, group}
+}
+
+//Has the same fields as SourceRef but not its methods, so that it marshals with the defaults of encoding/json.
+type sourceRefJSON struct {
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address,omitempty"`
+	Group   string `json:"group,omitempty"`
+}
+
+func (ref SourceRef) validate() error {
+	if ref.Name == "" && ref.Address == "" {
+		return fmt.Errorf("%w: source reference has neither a name nor an address", ErrInvalidSettings)
+	}
+	return nil
+}
+
+func (ref SourceRef) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sourceRefJSON(ref))
+}
+
+func (ref *SourceRef) UnmarshalJSON(data []byte) error {
+	var v sourceRefJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if err := SourceRef(v).validate(); err != nil {
+		return err
+	}
+	*ref = SourceRef(v)
+	return nil
+}
+
+func (ref SourceRef) MarshalText() ([]byte, error) {
+	v := url.Values{}
+	for _, f := range []struct{ key, value string }{{"name", ref.Name}, {"address", ref.Address}, {"group", ref.Group}} {
+		if f.value != "" {
+			v.Set(f.key, f.value)
+		}
+	}
+	return []byte(v.Encode()), nil
+}
+
+//UnmarshalText accepts the text MarshalText produces, as well as a bare source name. Unknown keys are ignored.
+func (ref *SourceRef) UnmarshalText(text []byte) error {
+	s := string(text)
+	if !strings.Contains(s, "=") {
+		if err := validateName("source name", s, maxSourceNameLen); err != nil {
+			return err
+		}
+		*ref = SourceRef{Name: s}
+		return nil
+	}
+
+	v, err := url.ParseQuery(s)
+	if err != nil {
+		return fmt.Errorf("%w: source reference %q: %v", ErrInvalidSettings, s, err)
+	}
+
+	r := SourceRef{v.Get("name"), v.Get("address"), v.Get("group")}
+	if err := r.validate(); err != nil {
+		return err
+	}
+	*ref = r
+	return nil
+}
+
+func (ref SourceRef) String() string {
+	text, _ := ref.MarshalText()
+	return string(text)
+}
+
+//Match returns the source with the name of the reference, or failing that the source at its address.
+func (ref SourceRef) Match(sources []SourceInfo) (SourceInfo, bool) {
+	if ref.Name != "" {
+		for _, s := range sources {
+			if s.Name == ref.Name {
+				return s, true
+			}
+		}
+	}
+
+	if ref.Address != "" {
+		for _, s := range sources {
+			if s.Address == ref.Address {
+				return s, true
+			}
+		}
+	}
+	return SourceInfo{}, false
+}
+
+//Resolve returns the source the reference matches among the current sources of find, ErrSourceNotFound if it
+//matches none.
+func (ref SourceRef) Resolve(find *FindInstance) (SourceInfo, error) {
+	sources, err := find.CurrentSources()
+	if err != nil {
+		return SourceInfo{}, err
+	}
+
+	s, ok := ref.Match(sources)
+	if !ok {
+		return SourceInfo{}, fmt.Errorf("%w: %s", ErrSourceNotFound, ref)
+	}
+	return s, nil
+}
+
+//ResolveContext is Resolve that waits up to timeout for a matching source to appear. It returns ctx.Err() when ctx
+//is done first.
+func (ref SourceRef) ResolveContext(ctx context.Context, find *FindInstance, timeout time.Duration) (SourceInfo, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		s, err := ref.Resolve(find)
+		if !errors.Is(err, ErrSourceNotFound) {
+			return s, err
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return SourceInfo{}, err
+		}
+
+		if _, err := find.WaitForSourcesContext(ctx, wait); err != nil {
+			return SourceInfo{}, err
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSourceRefMarshal(t *testing.T) {
+	ref := SourceRef{"STUDIO (Program & Preview)", "10.0.0.1:5961", "studio"}
+
+	type output struct {
+		Source SourceRef            `json:"source"`
+		ByName map[SourceRef]string `json:"by_name"`
+	}
+	data := must(json.Marshal(output{ref, map[SourceRef]string{ref: "x"}}))
+	expected := `{"source":{"name":"STUDIO (Program \u0026 Preview)","address":"10.0.0.1:5961","group":"studio"},` +
+		`"by_name":{"address=10.0.0.1%3A5961\u0026group=studio\u0026name=STUDIO+%28Program+%26+Preview%29":"x"}}`
+	if string(data) != expected {
+		t.Fatalf("Unexpected JSON %s.", data)
+	}
+
+	var out output
+	checkErr(t, json.Unmarshal(data, &out))
+	if out.Source != ref || out.ByName[ref] != "x" {
+		t.Errorf("Unexpected round trip %+v.", out)
+	}
+
+	var bare SourceRef
+	if err := bare.UnmarshalText([]byte("CAMERA (1)")); err != nil || bare != (SourceRef{Name: "CAMERA (1)"}) {
+		t.Errorf("Unexpected bare name %+v, %v.", bare, err)
+	}
+
+	for _, invalid := range []string{"", "group=studio", "name=%zz"} {
+		if err := new(SourceRef).UnmarshalText([]byte(invalid)); !errors.Is(err, ErrInvalidSettings) {
+			t.Errorf("Expected %q to be rejected, got %v.", invalid, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"group":"studio"}`), new(SourceRef)); !errors.Is(err, ErrInvalidSettings) {
+		t.Errorf("Expected a JSON reference without name and address to be rejected, got %v.", err)
+	}
+}
+
+func TestSourceRefMatch(t *testing.T) {
+	sources := []SourceInfo{{"CAMERA (1)", "10.0.0.2:5961"}, {"CAMERA (Renamed)", "10.0.0.1:5961"}}
+
+	//The name wins over the address.
+	if s, ok := (SourceRef{Name: "CAMERA (1)", Address: "10.0.0.1:5961"}).Match(sources); !ok || s != sources[0] {
+		t.Errorf("Expected a match by name, got %v.", s)
+	}
+	if s, ok := (SourceRef{Name: "CAMERA (Old)", Address: "10.0.0.1:5961"}).Match(sources); !ok || s != sources[1] {
+		t.Errorf("Expected a match by address, got %v.", s)
+	}
+	if s, ok := (SourceRef{Name: "CAMERA (2)"}).Match(sources); ok {
+		t.Errorf("Expected no match, got %v.", s)
+	}
+}
+
+func TestSourceRefResolve(t *testing.T) {
+	fake, pool := withFake(t)
+
+	find := must(NewFindInstanceV2(pool.NewFindCreateSettings(true, "", "")))
+	pool.Register(find)
+
+	ref := SourceRef{Name: "CAMERA (1)", Address: "10.0.0.1:5961"}
+	if _, err := ref.Resolve(find); !errors.Is(err, ErrSourceNotFound) {
+		t.Fatalf("Expected the source not to be found, got %v.", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		fake.AddSource("CAMERA (1)", "10.0.0.2:5961")
+	}()
+
+	if s, err := ref.ResolveContext(ctx, find, 5*time.Second); err != nil || s != (SourceInfo{"CAMERA (1)", "10.0.0.2:5961"}) {
+		t.Fatalf("Expected the source to be resolved once it appears, got %v, %v.", s, err)
+	}
+
+	if _, err := (SourceRef{Name: "CAMERA (2)"}).ResolveContext(ctx, find, 10*time.Millisecond); !errors.Is(err, ErrSourceNotFound) {
+		t.Errorf("Expected the wait to time out, got %v.", err)
+	}
+}
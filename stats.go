@@ -0,0 +1,130 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//Frames per second of each type.
+type RecvRates struct {
+	Video, Audio, Metadata float64
+}
+
+//Returns the rates at which the counts went from prev to cur over elapsed. A count that went down was reset by the
+//runtime, for example on reconnecting, and its rate is zero like in a first sample.
+func recvRates(prev, cur RecvPerformance, elapsed time.Duration) RecvRates {
+	if elapsed <= 0 {
+		return RecvRates{}
+	}
+
+	s := elapsed.Seconds()
+	rate := func(prev, cur int64) float64 {
+		return float64(max(cur-prev, 0)) / s
+	}
+	return RecvRates{
+		Video:    rate(prev.VideoFrames, cur.VideoFrames),
+		Audio:    rate(prev.AudioFrames, cur.AudioFrames),
+		Metadata: rate(prev.MetadataFrames, cur.MetadataFrames),
+	}
+}
+
+//RecvStats is one sample taken by a StatsSampler.
+type RecvStats struct {
+	Time           time.Time
+	Total, Dropped RecvPerformance
+	Queue          RecvQueue
+
+	//The rates at which frames were received and dropped since the previous sample, zero in the first one.
+	TotalRate, DroppedRate RecvRates
+}
+
+//StatsSampler samples the performance and queue statistics of a receive instance at a fixed interval on its own
+//goroutine.
+type StatsSampler struct {
+	recv *RecvInstance
+	fn   func(RecvStats)
+	worker
+
+	mu     sync.Mutex
+	latest RecvStats
+}
+
+//NewStatsSampler takes a sample right away and then every interval until ctx is done, calling fn with each one on the
+//sampler goroutine if it is not nil. The receive instance stays owned by the caller and must outlive the sampler. It
+//returns an error wrapping ErrInvalidSettings when interval is not positive.
+func NewStatsSampler(ctx context.Context, recv *RecvInstance, interval time.Duration, fn func(RecvStats)) (*StatsSampler, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%w: sampling interval %v is not positive", ErrInvalidSettings, interval)
+	}
+
+	s := &StatsSampler{recv: recv, fn: fn, worker: newWorker()}
+
+	first, err := s.sample(RecvStats{})
+	if err != nil {
+		return nil, err
+	}
+
+	go s.run(ctx, interval, first)
+	return s, nil
+}
+
+//Latest returns the last sample taken.
+func (s *StatsSampler) Latest() RecvStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latest
+}
+
+//Takes a sample and stores it as the latest, computing the rates since prev unless it is the zero value.
+func (s *StatsSampler) sample(prev RecvStats) (RecvStats, error) {
+	cur := RecvStats{Time: time.Now()}
+	if err := s.recv.GetPerformance(&cur.Total, &cur.Dropped); err != nil {
+		return RecvStats{}, err
+	}
+	if err := s.recv.GetQueue(&cur.Queue); err != nil {
+		return RecvStats{}, err
+	}
+
+	if !prev.Time.IsZero() {
+		elapsed := cur.Time.Sub(prev.Time)
+		cur.TotalRate = recvRates(prev.Total, cur.Total, elapsed)
+		cur.DroppedRate = recvRates(prev.Dropped, cur.Dropped, elapsed)
+	}
+
+	s.mu.Lock()
+	s.latest = cur
+	s.mu.Unlock()
+	return cur, nil
+}
+
+func (s *StatsSampler) run(ctx context.Context, interval time.Duration, prev RecvStats) {
+	s.stop(s.sampleEvery(ctx, interval, prev))
+}
+
+func (s *StatsSampler) sampleEvery(ctx context.Context, interval time.Duration, prev RecvStats) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if s.fn != nil {
+			s.fn(prev)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		var err error
+		if prev, err = s.sample(prev); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecvRates(t *testing.T) {
+	prev := RecvPerformance{VideoFrames: 100, AudioFrames: 200}
+	cur := RecvPerformance{VideoFrames: 160, AudioFrames: 200, MetadataFrames: 3}
+	if r := recvRates(prev, cur, 2*time.Second); r != (RecvRates{Video: 30, Metadata: 1.5}) {
+		t.Errorf("Unexpected rates %+v.", r)
+	}
+	if r := recvRates(prev, cur, 0); r != (RecvRates{}) {
+		t.Errorf("Expected no rates without elapsed time, got %+v.", r)
+	}
+	if r := recvRates(cur, prev, 2*time.Second); r != (RecvRates{}) {
+		t.Errorf("Expected no rates after the counts were reset, got %+v.", r)
+	}
+}
+
+func TestStatsSampler(t *testing.T) {
+	fake, _, recv := withFakeRecv(t, "CAMERA (1)")
+
+	fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeAudio, Audio: NewAudioFrameV2()})
+	fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeVideo, Video: NewVideoFrameV2()})
+	fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeVideo, Video: NewVideoFrameV2()})
+
+	//Capturing only video drops the audio frame in front of it.
+	var vf VideoFrameV2
+	if ft := must(recv.CaptureV2(&vf, nil, nil, 1000)); ft != FrameTypeVideo {
+		t.Fatalf("Expected a video frame, got %d.", ft)
+	}
+	checkErr(t, recv.FreeVideoV2(&vf))
+
+	var total, dropped RecvPerformance
+	checkErr(t, recv.GetPerformance(&total, &dropped))
+	if total != (RecvPerformance{VideoFrames: 2, AudioFrames: 1}) || dropped != (RecvPerformance{AudioFrames: 1}) {
+		t.Errorf("Unexpected performance %+v, dropped %+v.", total, dropped)
+	}
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := NewStatsSampler(context.Background(), recv, interval, nil); !errors.Is(err, ErrInvalidSettings) {
+			t.Errorf("Expected an interval of %v to be rejected, got %v.", interval, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	samples := make(chan RecvStats, 16)
+	s := must(NewStatsSampler(ctx, recv, 20*time.Millisecond, func(st RecvStats) {
+		select {
+		case samples <- st:
+		default:
+		}
+	}))
+	if q := s.Latest().Queue; q != (RecvQueue{VideoFrames: 1}) {
+		t.Errorf("Unexpected queue %+v.", q)
+	}
+
+	<-samples
+	for i := 0; i < 10; i++ {
+		fake.Deliver("CAMERA (1)", FakeFrame{Type: FrameTypeVideo, Video: NewVideoFrameV2()})
+	}
+
+	for timeout := time.After(5 * time.Second); ; {
+		select {
+		case st := <-samples:
+			if st.TotalRate.Audio != 0 {
+				t.Errorf("Unexpected rates %+v.", st.TotalRate)
+			}
+			if st.TotalRate.Video == 0 {
+				continue
+			}
+		case <-timeout:
+			t.Fatal("Timed out waiting for a sample with the new frames.")
+		}
+		break
+	}
+
+	cancel()
+	if err := s.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the sampler to be canceled, got %v.", err)
+	}
+}
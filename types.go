@@ -5,7 +5,6 @@
 package ndi
 
 import (
-	"fmt"
 	"math"
 	"reflect"
 	"syscall"
@@ -22,22 +21,42 @@ func goStringFromConst(p uintptr) string {
 	return string(*(*[]byte)(unsafe.Pointer(h)))
 }
 
-func goStringFromCString(p uintptr) string {
-	s := ""
-	for ; *(*byte)(unsafe.Pointer(p)) != 0; p++ {
-		s = fmt.Sprintf("%s%c", s, *(*byte)(unsafe.Pointer(p)))
+//Returns a copy of the NULL terminated string at p, which may point to Go or C memory.
+func goStringFromPtr(p *byte) string {
+	if p == nil {
+		return ""
 	}
-	return s
+	return string(unsafe.Slice(p, cStringLen(p)))
+}
+
+//Returns a NULL terminated copy of s, or nil for the empty string.
+func cStringPtr(s string) *byte {
+	if s == "" {
+		return nil
+	}
+
+	b := make([]byte, len(s)+1)
+	copy(b, s)
+	return &b[0]
 }
 
 type Error struct {
 	syscall.Errno
 }
 
-func (e *Error) Timeout() bool {
+func (e Error) Timeout() bool {
 	return e.Errno.Timeout() || uintptr(e.Errno) == 1460
 }
 
+func (e Error) Unwrap() error {
+	return e.Errno
+}
+
+//Reports timeouts as ErrTimeout, the system error number itself is matched through Unwrap.
+func (e Error) Is(target error) bool {
+	return target == ErrTimeout && e.Timeout()
+}
+
 type FrameFormat int32
 
 const (
@@ -197,6 +216,31 @@ func (af *AudioFrameV2) SetDefault() {
 	af.Timestamp = SendTimecodeEmpty
 }
 
+//This describes an interleaved audio buffer with 16 bit samples.
+type AudioFrameInterleaved16s struct {
+	SampleRate, //The sample-rate of this buffer.
+	NumChannels, //The number of audio channels.
+	NumSamples int32 //The number of audio samples per channel.
+	Timecode int64 //The timecode of this frame in 100ns intervals.
+
+	//The audio reference level in dB. This specifies how many dB above the reference level (+4dBU) is the full range
+	//of 16 bit audio. If you do not understand this and want to just use numbers:
+	//- If you are sending audio, specify +0dB. Most common applications produce audio at reference level.
+	//- If receiving audio, specify +20dB. This means that the full 16 bit range corresponds to professional level audio with 20dB of headroom.
+	ReferenceLevel int32
+
+	Data *int16 //The audio data, interleaved 16 bit.
+}
+
+//This describes an interleaved audio buffer with floating point samples.
+type AudioFrameInterleaved32f struct {
+	SampleRate, //The sample-rate of this buffer.
+	NumChannels, //The number of audio channels.
+	NumSamples int32 //The number of audio samples per channel.
+	Timecode int64 //The timecode of this frame in 100ns intervals.
+	Data     *float32 //The audio data, interleaved 32 bit.
+}
+
 func NewRecvCreateSettings() *RecvCreateSettings {
 	s := &RecvCreateSettings{}
 	s.SetDefault()
@@ -229,6 +273,25 @@ func (s *RecvCreateSettings) SetDefault() {
 	s.AllowVideoFields = true
 }
 
+//Frame counts of a receiver, either of every frame received or of the frames that were dropped.
+type RecvPerformance struct {
+	VideoFrames, AudioFrames, MetadataFrames int64
+}
+
+//The number of frames of each type that are waiting to be captured.
+type RecvQueue struct {
+	VideoFrames, AudioFrames, MetadataFrames int32
+}
+
+//The times of a recording made by a source.
+type RecvRecordingTime struct {
+	//The number of video frames recorded.
+	NoFrames int64
+
+	//The time of the first and of the latest frame in the recording, in UTC at 100ns intervals since the Unix epoch.
+	StartTime, LastTime int64
+}
+
 func NewMetadataFrame() *MetadataFrame {
 	mf := &MetadataFrame{}
 	mf.SetDefault()
@@ -345,3 +408,53 @@ type ndiLIBv3 struct {
 	NDIlibRecvRecordingGetError, //const char*(*NDIlib_recv_recording_get_error)(NDIlib_recv_instance_t p_instance)
 	NDIlibRecvRecordingGetTimes uintptr //bool(*NDIlib_recv_recording_get_times)(NDIlib_recv_instance_t p_instance, NDIlib_recv_recording_time_t* p_times)
 }
+
+//The v4 table starts with the v3 table, the runtime only ever appends to it.
+type ndiLIBv4 struct {
+	ndiLIBv3
+
+	// V3.1
+	NDIlibRecvCreateV3, //NDIlib_recv_instance_t(*NDIlib_recv_create_v3)(const NDIlib_recv_create_v3_t* p_create_settings)
+
+	// V3.5
+	NDIlibRecvConnect, //void(*NDIlib_recv_connect)(NDIlib_recv_instance_t p_instance, const NDIlib_source_t* p_src)
+
+	// V3.6
+	NDIlibFramesyncCreate, //NDIlib_framesync_instance_t(*NDIlib_framesync_create)(NDIlib_recv_instance_t p_receiver)
+	NDIlibFramesyncDestroy, //void(*NDIlib_framesync_destroy)(NDIlib_framesync_instance_t p_instance)
+	NDIlibFramesyncCaptureAudio, //void(*NDIlib_framesync_capture_audio)(NDIlib_framesync_instance_t p_instance, NDIlib_audio_frame_v2_t* p_audio_data, int sample_rate, int no_channels, int no_samples)
+	NDIlibFramesyncFreeAudio, //void(*NDIlib_framesync_free_audio)(NDIlib_framesync_instance_t p_instance, NDIlib_audio_frame_v2_t* p_audio_data)
+	NDIlibFramesyncCaptureVideo, //void(*NDIlib_framesync_capture_video)(NDIlib_framesync_instance_t p_instance, NDIlib_video_frame_v2_t* p_video_data, NDIlib_frame_format_type_e field_type)
+	NDIlibFramesyncFreeVideo, //void(*NDIlib_framesync_free_video)(NDIlib_framesync_instance_t p_instance, NDIlib_video_frame_v2_t* p_video_data)
+	NDIlibUtilSendSendAudioInterleaved32s, //void(*NDIlib_util_send_send_audio_interleaved_32s)(NDIlib_send_instance_t p_instance, const NDIlib_audio_frame_interleaved_32s_t* p_audio_data)
+	NDIlibUtilAudioToInterleaved32sV2, //void(*NDIlib_util_audio_to_interleaved_32s_v2)(const NDIlib_audio_frame_v2_t* p_src, NDIlib_audio_frame_interleaved_32s_t* p_dst)
+	NDIlibUtilAudioFromInterleaved32sV2, //void(*NDIlib_util_audio_from_interleaved_32s_v2)(const NDIlib_audio_frame_interleaved_32s_t* p_src, NDIlib_audio_frame_v2_t* p_dst)
+
+	// V3.8
+	NDIlibSendGetSourceName, //const NDIlib_source_t* (*NDIlib_send_get_source_name)(NDIlib_send_instance_t p_instance)
+
+	// V4
+	NDIlibSendSendAudioV3, //void(*NDIlib_send_send_audio_v3)(NDIlib_send_instance_t p_instance, const NDIlib_audio_frame_v3_t* p_audio_data)
+	NDIlibUtilV210ToP216, //void(*NDIlib_util_V210_to_P216)(const NDIlib_video_frame_v2_t* p_src_v210, NDIlib_video_frame_v2_t* p_dst_p216)
+	NDIlibUtilP216ToV210, //void(*NDIlib_util_P216_to_V210)(const NDIlib_video_frame_v2_t* p_src_p216, NDIlib_video_frame_v2_t* p_dst_v210)
+
+	// V4.1
+	NDIlibRoutingGetNoConnections, //int(*NDIlib_routing_get_no_connections)(NDIlib_routing_instance_t p_instance, uint32_t timeout_in_ms)
+	NDIlibRoutingGetSourceName, //const NDIlib_source_t* (*NDIlib_routing_get_source_name)(NDIlib_routing_instance_t p_instance)
+	NDIlibRecvCaptureV3, //NDIlib_frame_type_e(*NDIlib_recv_capture_v3)(NDIlib_recv_instance_t p_instance, NDIlib_video_frame_v2_t* p_video_data, NDIlib_audio_frame_v3_t* p_audio_data, NDIlib_metadata_frame_t* p_metadata, uint32_t timeout_in_ms)
+	NDIlibRecvFreeAudioV3, //void(*NDIlib_recv_free_audio_v3)(NDIlib_recv_instance_t p_instance, const NDIlib_audio_frame_v3_t* p_audio_data)
+	NDIlibFramesyncCaptureAudioV2, //void(*NDIlib_framesync_capture_audio_v2)(NDIlib_framesync_instance_t p_instance, NDIlib_audio_frame_v3_t* p_audio_data, int sample_rate, int no_channels, int no_samples)
+	NDIlibFramesyncFreeAudioV2, //void(*NDIlib_framesync_free_audio_v2)(NDIlib_framesync_instance_t p_instance, NDIlib_audio_frame_v3_t* p_audio_data)
+	NDIlibFramesyncAudioQueueDepth uintptr //int(*NDIlib_framesync_audio_queue_depth)(NDIlib_framesync_instance_t p_instance)
+}
+
+//The v5 table starts with the v4 table.
+type ndiLIBv5 struct {
+	ndiLIBv4
+
+	// V4.5
+	NDIlibRecvPtzExposureManualV2, //bool(*NDIlib_recv_ptz_exposure_manual_v2)(NDIlib_recv_instance_t p_instance, const float iris, const float gain, const float shutter_speed)
+
+	// V5
+	NDIlibRecvGetSourceName uintptr //bool(*NDIlib_recv_get_source_name)(NDIlib_recv_instance_t p_instance, const char** p_source_name, uint32_t timeout_in_ms)
+}
@@ -70,4 +70,31 @@ func TestStructSizes(t *testing.T) {
 
 	var fcs FindCreateSettings
 	checkTypeSize(t, fcs, 24)
+
+	var rcs RoutingCreateSettings
+	checkTypeSize(t, rcs, 16)
+
+	var af16 AudioFrameInterleaved16s
+	checkTypeSize(t, af16, 40)
+
+	var af32 AudioFrameInterleaved32f
+	checkTypeSize(t, af32, 32)
+
+	var perf RecvPerformance
+	checkTypeSize(t, perf, 24)
+
+	var queue RecvQueue
+	checkTypeSize(t, queue, 12)
+
+	var times RecvRecordingTime
+	checkTypeSize(t, times, 24)
+
+	var v3 ndiLIBv3
+	checkTypeSize(t, v3, 672)
+
+	var v4 ndiLIBv4
+	checkTypeSize(t, v4, 848)
+
+	var v5 ndiLIBv5
+	checkTypeSize(t, v5, 864)
 }
@@ -0,0 +1,69 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+//This will add an audio frame in interleaved 16 bit.
+func (inst *SendInstance) SendAudioInterleaved16s(frame *AudioFrameInterleaved16s) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return b.UtilSendSendAudioInterleaved16s(inst.handle, frame)
+}
+
+//This will add an audio frame in interleaved floating point.
+func (inst *SendInstance) SendAudioInterleaved32f(frame *AudioFrameInterleaved32f) error {
+	b, err := inst.rlock()
+	if err != nil {
+		return err
+	}
+	defer inst.runlock()
+	return b.UtilSendSendAudioInterleaved32f(inst.handle, frame)
+}
+
+//Convert a planar floating point audio frame into interleaved 16 bit. The data of dst must be allocated by the
+//caller and hold NumSamples*NumChannels samples.
+func AudioToInterleaved16sV2(src *AudioFrameV2, dst *AudioFrameInterleaved16s) error {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return err
+	}
+	defer l.mu.RUnlock()
+	return b.UtilAudioToInterleaved16sV2(src, dst)
+}
+
+//Convert an interleaved 16 bit audio frame into planar floating point. The data of dst must be allocated by the
+//caller and hold NumSamples samples for each of the NumChannels channels, ChannelStride bytes apart.
+func AudioFromInterleaved16sV2(src *AudioFrameInterleaved16s, dst *AudioFrameV2) error {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return err
+	}
+	defer l.mu.RUnlock()
+	return b.UtilAudioFromInterleaved16sV2(src, dst)
+}
+
+//Convert a planar floating point audio frame into interleaved floating point. The data of dst must be allocated by
+//the caller and hold NumSamples*NumChannels samples.
+func AudioToInterleaved32fV2(src *AudioFrameV2, dst *AudioFrameInterleaved32f) error {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return err
+	}
+	defer l.mu.RUnlock()
+	return b.UtilAudioToInterleaved32fV2(src, dst)
+}
+
+//Convert an interleaved floating point audio frame into planar floating point. The data of dst must be allocated by
+//the caller and hold NumSamples samples for each of the NumChannels channels, ChannelStride bytes apart.
+func AudioFromInterleaved32fV2(src *AudioFrameInterleaved32f, dst *AudioFrameV2) error {
+	l, b, err := loadedLibrary()
+	if err != nil {
+		return err
+	}
+	defer l.mu.RUnlock()
+	return b.UtilAudioFromInterleaved32fV2(src, dst)
+}
@@ -0,0 +1,206 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type SourceEventType int
+
+const (
+	SourceAdded SourceEventType = iota
+	SourceRemoved
+
+	//The source was renamed or moved to a different address. Sources are matched by name first, then by address.
+	SourceChanged
+)
+
+func (t SourceEventType) String() string {
+	switch t {
+	case SourceAdded:
+		return "added"
+	case SourceRemoved:
+		return "removed"
+	case SourceChanged:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+type SourceEvent struct {
+	Type   SourceEventType
+	Source SourceInfo
+
+	//The source as it was before a SourceChanged event.
+	Previous SourceInfo
+}
+
+//How long a watcher waits for the finder in one go, this bounds how long it takes to notice that its context is done.
+const watchWaitTimeout = time.Second
+
+//Watcher follows the sources a finder sees on its own goroutine and reports the differences as events. The sources
+//that are online when it starts are reported as SourceAdded events first.
+type Watcher struct {
+	find   *FindInstance
+	events chan SourceEvent
+	worker
+
+	mu      sync.Mutex
+	sources []SourceInfo
+}
+
+//NewWatcher starts watching the sources of find until ctx is done. Events are delivered on the Events channel, which
+//must be drained. The finder stays owned by the caller and must outlive the watcher.
+func NewWatcher(ctx context.Context, find *FindInstance) (*Watcher, error) {
+	w, err := newWatcher(find)
+	if err != nil {
+		return nil, err
+	}
+
+	w.events = make(chan SourceEvent, 16)
+	go func() {
+		defer close(w.events)
+		w.run(ctx, func(ev SourceEvent) bool {
+			select {
+			case w.events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return w, nil
+}
+
+//NewWatcherFunc is NewWatcher that calls fn on the watcher goroutine for every event instead of using a channel.
+func NewWatcherFunc(ctx context.Context, find *FindInstance, fn func(SourceEvent)) (*Watcher, error) {
+	w, err := newWatcher(find)
+	if err != nil {
+		return nil, err
+	}
+
+	go w.run(ctx, func(ev SourceEvent) bool {
+		fn(ev)
+		return true
+	})
+	return w, nil
+}
+
+func newWatcher(find *FindInstance) (*Watcher, error) {
+	sources, err := find.CurrentSources()
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{find: find, worker: newWorker(), sources: sources}, nil
+}
+
+//Events returns the channel events are delivered on, it is closed when the watcher stops. It is nil for watchers
+//created with NewWatcherFunc.
+func (w *Watcher) Events() <-chan SourceEvent {
+	return w.events
+}
+
+//Sources returns the sources as of the last change the watcher saw.
+func (w *Watcher) Sources() []SourceInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]SourceInfo(nil), w.sources...)
+}
+
+func (w *Watcher) run(ctx context.Context, emit func(SourceEvent) bool) {
+	err := w.watch(ctx, emit)
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	w.stop(err)
+}
+
+func (w *Watcher) watch(ctx context.Context, emit func(SourceEvent) bool) error {
+	for _, s := range w.Sources() {
+		if !emit(SourceEvent{Type: SourceAdded, Source: s}) {
+			return nil
+		}
+	}
+
+	for {
+		changed, err := w.find.WaitForSourcesContext(ctx, watchWaitTimeout)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			continue
+		}
+
+		sources, err := w.find.CurrentSources()
+		if err != nil {
+			return err
+		}
+
+		w.mu.Lock()
+		events := diffSources(w.sources, sources)
+		w.sources = sources
+		w.mu.Unlock()
+
+		for _, ev := range events {
+			if !emit(ev) {
+				return nil
+			}
+		}
+	}
+}
+
+//Returns the events that turn the old into the current list of sources. Sources with the same name but a different
+//address, and sources that disappeared under one name and appeared under another at the same address, are reported
+//as changed.
+func diffSources(old, cur []SourceInfo) []SourceEvent {
+	oldByName := make(map[string]SourceInfo, len(old))
+	for _, s := range old {
+		oldByName[s.Name] = s
+	}
+	curByName := make(map[string]SourceInfo, len(cur))
+	for _, s := range cur {
+		curByName[s.Name] = s
+	}
+
+	var events []SourceEvent
+	removedByAddress := make(map[string]SourceInfo)
+	var removed []SourceInfo
+	for _, s := range old {
+		if _, ok := curByName[s.Name]; !ok {
+			removed = append(removed, s)
+			if s.Address != "" {
+				removedByAddress[s.Address] = s
+			}
+		}
+	}
+
+	renamed := make(map[string]bool)
+	for _, s := range cur {
+		prev, ok := oldByName[s.Name]
+		switch {
+		case !ok:
+			if r, ok := removedByAddress[s.Address]; ok && s.Address != "" && !renamed[r.Name] {
+				renamed[r.Name] = true
+				events = append(events, SourceEvent{Type: SourceChanged, Source: s, Previous: r})
+			} else {
+				events = append(events, SourceEvent{Type: SourceAdded, Source: s})
+			}
+		case prev.Address != s.Address:
+			events = append(events, SourceEvent{Type: SourceChanged, Source: s, Previous: prev})
+		}
+	}
+
+	for _, s := range removed {
+		if !renamed[s.Name] {
+			events = append(events, SourceEvent{Type: SourceRemoved, Source: s})
+		}
+	}
+	return events
+}
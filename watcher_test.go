@@ -0,0 +1,92 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, w *Watcher) SourceEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-w.Events():
+		if !ok {
+			t.Fatalf("Watcher stopped: %v.", w.Wait())
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a source event.")
+	}
+	return SourceEvent{}
+}
+
+func TestWatcher(t *testing.T) {
+	fake, pool := withFake(t)
+	fake.AddSource("CAMERA (1)", "10.0.0.1:5961")
+
+	find := must(NewFindInstanceV2(pool.NewFindCreateSettings(true, "", "")))
+	pool.Register(find)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := must(NewWatcher(ctx, find))
+
+	camera1 := SourceInfo{"CAMERA (1)", "10.0.0.1:5961"}
+	if ev := nextEvent(t, w); ev != (SourceEvent{Type: SourceAdded, Source: camera1}) {
+		t.Errorf("Expected the initial source to be added, got %v.", ev)
+	}
+
+	fake.AddSource("CAMERA (2)", "10.0.0.2:5961")
+	camera2 := SourceInfo{"CAMERA (2)", "10.0.0.2:5961"}
+	if ev := nextEvent(t, w); ev != (SourceEvent{Type: SourceAdded, Source: camera2}) {
+		t.Errorf("Expected the second source to be added, got %v.", ev)
+	}
+
+	fake.RemoveSource("CAMERA (1)")
+	if ev := nextEvent(t, w); ev != (SourceEvent{Type: SourceRemoved, Source: camera1}) {
+		t.Errorf("Expected the first source to be removed, got %v.", ev)
+	}
+
+	fake.AddSource("CAMERA (2)", "10.0.0.3:5961")
+	moved := SourceInfo{"CAMERA (2)", "10.0.0.3:5961"}
+	if ev := nextEvent(t, w); ev != (SourceEvent{Type: SourceChanged, Source: moved, Previous: camera2}) {
+		t.Errorf("Expected the second source to move, got %v.", ev)
+	}
+
+	if sources := w.Sources(); len(sources) != 1 || sources[0] != moved {
+		t.Errorf("Unexpected sources %v.", sources)
+	}
+
+	cancel()
+	if err := w.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v.", err)
+	}
+	if _, ok := <-w.Events(); ok {
+		t.Error("The event channel is still open after the watcher stopped.")
+	}
+}
+
+func TestDiffSources(t *testing.T) {
+	old := []SourceInfo{{"A (1)", "10.0.0.1:5961"}, {"B (1)", "10.0.0.2:5961"}, {"C (1)", ""}}
+	cur := []SourceInfo{{"A (2)", "10.0.0.1:5961"}, {"B (1)", "10.0.0.2:5961"}, {"D (1)", ""}}
+
+	events := diffSources(old, cur)
+	want := []SourceEvent{
+		{Type: SourceChanged, Source: cur[0], Previous: old[0]},
+		{Type: SourceAdded, Source: cur[2]},
+		{Type: SourceRemoved, Source: old[2]},
+	}
+
+	if len(events) != len(want) {
+		t.Fatalf("Expected %v, got %v.", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("Expected %v, got %v.", want[i], events[i])
+		}
+	}
+}
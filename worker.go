@@ -0,0 +1,35 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ndi
+
+//Tells when the goroutine of a background object has stopped and why. Objects embed it for their Done and Wait
+//methods.
+type worker struct {
+	done chan struct{}
+
+	//Only written before done is closed, so it needs no lock once done is.
+	err error
+}
+
+func newWorker() worker {
+	return worker{done: make(chan struct{})}
+}
+
+//Done is closed when the goroutine has stopped.
+func (w *worker) Done() <-chan struct{} {
+	return w.done
+}
+
+//Wait blocks until the goroutine has stopped and returns why, ctx.Err() when its context is done or it was closed.
+func (w *worker) Wait() error {
+	<-w.done
+	return w.err
+}
+
+//Records why the goroutine stopped and closes done, it must be called once as the goroutine returns.
+func (w *worker) stop(err error) {
+	w.err = err
+	close(w.done)
+}